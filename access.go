@@ -0,0 +1,223 @@
+package truth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RoleAnonymous is the role of callers presenting no credentials. It is tested
+// with the Anonymous credentials and needs no registered Identity.
+const RoleAnonymous = "anonymous"
+
+type (
+	// AccessPolicy maps a role to the status code the Definition responds with
+	// when called by that role:
+	//
+	//	def.Access = truth.AccessPolicy{
+	//		"admin":             200,
+	//		"owner":             200,
+	//		"member":            403,
+	//		truth.RoleAnonymous: 401,
+	//	}
+	//
+	// Every role other than RoleAnonymous must be registered as an Identity.
+	AccessPolicy map[string]int
+
+	// AccessMatrix collects the status codes observed for each Definition and
+	// role. Print it to review the access rules of an API at a glance.
+	AccessMatrix struct {
+		mu    sync.Mutex
+		rows  map[string]map[string]accessCell
+		roles map[string]bool
+	}
+
+	accessCell struct {
+		expected, received int
+	}
+)
+
+// DefaultAccessMatrix collects the results of RunAccessTests. Print it from
+// TestMain once the tests have run to report every Definition and role:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		fmt.Print(truth.DefaultAccessMatrix)
+//		os.Exit(code)
+//	}
+var DefaultAccessMatrix = &AccessMatrix{}
+
+// RunAccessTests runs every test case once per role of the Definition's Access
+// policy using the Identity registered for the role. Each run must respond with
+// the status code the policy expects for the role. A role receiving a status
+// other than the one expected, for example a member allowed to call an admin
+// endpoint, fails the test. Provide a client to perform full-stack tests. If nil
+// is provided the server's Mux will be called directly.
+//
+// A role the policy allows, with a 2XX status, must receive the Status of the
+// test case, when it has one, so cases expecting `404 Not Found` or `409
+// Conflict` test access too. A role given any other status, such as `403
+// Forbidden` or `405 Method Not Allowed`, must receive that status. The credential headers of the test case are
+// removed so each run is authorized only by its role.
+//
+// Results are added to the DefaultAccessMatrix.
+func RunAccessTests(t *testing.T, def Definition, cases TestCases, c *Client) error {
+	if len(def.Access) == 0 {
+		t.Fatalf("Definition `%s:%s` has no Access policy", def.Method, def.Path)
+	}
+
+	cases.init(def, getCaller(2))
+	Register(def, cases)
+
+	roles := make([]string, 0, len(def.Access))
+	for role := range def.Access {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	for _, tc := range cases {
+		for _, role := range roles {
+			rc := *tc
+			rc.Headers = copyHeaders(tc.Headers)
+			AnonymousVariant.Alter(&rc)
+			if role != RoleAnonymous {
+				rc.Identity = Identity(role)
+			}
+			rc.alias = fmt.Sprintf("%s [as %s]", tc.alias, role)
+
+			if printTestRuns {
+				fmt.Printf("Running %#v\n", rc.alias)
+			}
+
+			RR, _, err := exchange(c, def, rc)
+			if err == errNoMux {
+				t.Fatal(err)
+			}
+			if err != nil {
+				t.Error(err)
+				return err
+			}
+
+			expected := expectedAccess(def.Access[role], rc)
+			DefaultAccessMatrix.Add(def, role, expected, RR.Code)
+
+			if RR.Code != expected {
+				t.Errorf("%s: Expected statuscode %d for role %#v but received %d at `%s:%s`", rc.alias, expected, role, RR.Code, def.Method, rc.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// expectedAccess returns the status code a role must receive. A role which is
+// allowed, with a 2XX status, receives the status the test case expects. Any
+// other status of the policy is expected as it is.
+func expectedAccess(policy int, tc TestCase) int {
+	if policy < 200 || policy > 299 || tc.Status == 0 {
+		return policy
+	}
+	return tc.Status
+}
+
+// Add records the status code received by the role. A Definition tested by
+// several test cases keeps the first unexpected result.
+func (m *AccessMatrix) Add(def Definition, role string, expected, received int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.rows == nil {
+		m.rows = map[string]map[string]accessCell{}
+		m.roles = map[string]bool{}
+	}
+
+	key := def.Method + " " + def.Path
+	if m.rows[key] == nil {
+		m.rows[key] = map[string]accessCell{}
+	}
+	m.roles[role] = true
+
+	if cell, ok := m.rows[key][role]; ok && cell.expected != cell.received {
+		return
+	}
+	m.rows[key][role] = accessCell{expected: expected, received: received}
+}
+
+// Failed reports if any role received a status code other than expected.
+func (m *AccessMatrix) Failed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.rows {
+		for _, cell := range row {
+			if cell.expected != cell.received {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String renders the matrix as a table with a row per Definition and a column
+// per role. A cell shows the status received and, when it differs, the status
+// expected:
+//
+//	ENDPOINT          admin  anonymous  member
+//	GET /users        200    401        403!=200
+func (m *AccessMatrix) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := make([]string, 0, len(m.roles))
+	for role := range m.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	keys := make([]string, 0, len(m.rows))
+	for k := range m.rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	table := [][]string{append([]string{"ENDPOINT"}, roles...)}
+	for _, k := range keys {
+		row := []string{k}
+		for _, role := range roles {
+			cell, ok := m.rows[k][role]
+			switch {
+			case !ok:
+				row = append(row, "-")
+			case cell.expected != cell.received:
+				row = append(row, fmt.Sprintf("%d!=%d", cell.received, cell.expected))
+			default:
+				row = append(row, fmt.Sprintf("%d", cell.received))
+			}
+		}
+		table = append(table, row)
+	}
+
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, v := range row {
+			if len(v) > widths[i] {
+				widths[i] = len(v)
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range table {
+		for i, v := range row {
+			if i < len(row)-1 {
+				v += strings.Repeat(" ", widths[i]-len(v)+2)
+			}
+			b.WriteString(v)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package truth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpectedAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   int
+		status   int
+		expected int
+	}{
+		{name: "allowed without status", policy: 200, expected: 200},
+		{name: "allowed with status", policy: 200, status: 404, expected: 404},
+		{name: "unauthorized ignores status", policy: 401, status: 404, expected: 401},
+		{name: "forbidden ignores status", policy: 403, status: 409, expected: 403},
+		{name: "not found ignores status", policy: 404, status: 201, expected: 404},
+		{name: "method not allowed ignores status", policy: 405, status: 200, expected: 405},
+		{name: "allowed without content", policy: 204, status: 201, expected: 201},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := expectedAccess(test.policy, TestCase{Status: test.status}); actual != test.expected {
+				t.Errorf("Expected %d but received %d", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunAccessTests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Header.Get("Authorization") {
+		case "":
+			rw.WriteHeader(http.StatusUnauthorized)
+		case "Bearer member":
+			if strings.HasPrefix(req.URL.Path, "/leaky") {
+				rw.WriteHeader(http.StatusOK)
+				return
+			}
+			rw.WriteHeader(http.StatusForbidden)
+		default:
+			if strings.HasSuffix(req.URL.Path, "/missing") {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	c.SetIdentity("admin", Bearer("admin"))
+	c.SetIdentity("member", Bearer("member"))
+
+	policy := AccessPolicy{
+		"admin":       200,
+		"member":      403,
+		RoleAnonymous: 401,
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		cases    TestCases
+		ok       bool
+		expected string
+	}{
+		{
+			name: "enforced",
+			path: "/items/{id}",
+			cases: TestCases{
+				{Name: "found", Path: "/items/1"},
+				{Name: "missing", Path: "/items/missing", Status: 404},
+				{Name: "credential headers", Path: "/items/1", Headers: map[string]string{"Authorization": "Bearer admin"}},
+			},
+			ok:       true,
+			expected: "ENDPOINT         admin  anonymous  member\nGET /items/{id}  200    401        403\n",
+		},
+		{
+			name:     "member allowed",
+			path:     "/leaky/{id}",
+			cases:    TestCases{{Name: "found", Path: "/leaky/1"}},
+			expected: "ENDPOINT         admin  anonymous  member\nGET /leaky/{id}  200    401        200!=403\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withRegistry(t)
+			matrix := DefaultAccessMatrix
+			DefaultAccessMatrix = &AccessMatrix{}
+			defer func() { DefaultAccessMatrix = matrix }()
+
+			def := Definition{
+				Method:           "GET",
+				Path:             test.path,
+				MIMETypeRequest:  MIMETypeJSON,
+				MIMETypeResponse: MIMETypeJSON,
+				Access:           policy,
+			}
+
+			ok := runShared(func(t *testing.T) {
+				RunAccessTests(t, def, test.cases, c)
+			})
+			if ok != test.ok {
+				t.Errorf("Expected the access tests to pass to be %t but received %t", test.ok, ok)
+			}
+			if DefaultAccessMatrix.Failed() == test.ok {
+				t.Errorf("Expected the matrix to fail to be %t", !test.ok)
+			}
+			if actual := DefaultAccessMatrix.String(); actual != test.expected {
+				t.Errorf("Expected:\n%s\nReceived:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAccessMatrixString(t *testing.T) {
+	m := &AccessMatrix{}
+	def := Definition{Method: "GET", Path: "/users"}
+	m.Add(def, "admin", 200, 200)
+	m.Add(def, "member", 403, 200)
+	m.Add(def, "member", 403, 403)
+
+	if !m.Failed() {
+		t.Error("Expected the matrix to fail when a role received an unexpected status")
+	}
+
+	expected := "ENDPOINT    admin  member\nGET /users  200    200!=403\n"
+	if actual := m.String(); actual != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, actual)
+	}
+}
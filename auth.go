@@ -0,0 +1,190 @@
+package truth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type (
+	// AuthVariant alters a test case to simulate a caller presenting missing or
+	// invalid credentials.
+	AuthVariant struct {
+		Name  string
+		Alter func(tc *TestCase)
+	}
+)
+
+// CredentialHeaders lists the headers which carry credentials. The
+// authentication tests remove or corrupt these headers and they are redacted
+// wherever exchanges are recorded, exported or printed.
+var CredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key", "X-Auth-Token", SignatureHeader}
+
+var (
+	// AnonymousVariant removes every credential from the test case.
+	AnonymousVariant = AuthVariant{
+		Name: "anonymous",
+		Alter: func(tc *TestCase) {
+			tc.Identity = Anonymous
+			for _, h := range CredentialHeaders {
+				deleteHeader(tc.Headers, h)
+			}
+		},
+	}
+
+	// MalformedVariant corrupts the credentials of the test case. An Authorization
+	// header keeps its scheme so the credentials are rejected for their content
+	// rather than their format.
+	MalformedVariant = AuthVariant{
+		Name: "malformed",
+		Alter: func(tc *TestCase) {
+			tc.Identity = Anonymous
+			if tc.Headers == nil {
+				tc.Headers = map[string]string{}
+			}
+			found := false
+			for _, h := range CredentialHeaders {
+				v, ok := getHeader(tc.Headers, h)
+				if !ok {
+					continue
+				}
+				found = true
+				if scheme := strings.SplitN(v, " ", 2); len(scheme) == 2 {
+					v = scheme[0] + " " + malformed
+				} else {
+					v = malformed
+				}
+				deleteHeader(tc.Headers, h)
+				tc.Headers[h] = v
+			}
+			if !found {
+				tc.Headers["Authorization"] = "Bearer " + malformed
+			}
+		},
+	}
+)
+
+const malformed = "!!malformed-credentials!!"
+
+// ExpiredVariant replaces the credentials of the test case with the provided
+// credentials, which should have been valid but have expired. Truth cannot
+// fabricate expired credentials for your application so they must be supplied.
+func ExpiredVariant(cred Credentials) AuthVariant {
+	return AuthVariant{
+		Name: "expired",
+		Alter: func(tc *TestCase) {
+			AnonymousVariant.Alter(tc)
+			tc.Identity = cred
+		},
+	}
+}
+
+// RunAuthTests replays the test cases of every registered Definition to verify
+// the endpoint enforces authentication. Provide a client to perform full-stack
+// tests. If nil is provided the server's Mux will be called directly.
+//
+// Definitions requiring authentication are replayed once per variant and must
+// respond with `401 Unauthorized` or `403 Forbidden`. An endpoint that responds
+// with anything else leaks data to callers without valid credentials and fails
+// the test. When no variants are provided the AnonymousVariant and the
+// MalformedVariant are used.
+//
+// Definitions using AuthorizationNone are replayed anonymously and must not
+// respond with `401 Unauthorized` or `403 Forbidden`.
+//
+// Only registered Definitions are replayed. RunIntegrationTests registers the
+// Definitions it runs, which a `-run` filter or the order of the tests may
+// prevent, so Register the Definitions of the API before running the
+// authentication tests. The test is skipped when nothing is registered and fails
+// for a Definition requiring authentication without test cases to replay.
+func RunAuthTests(t *testing.T, c *Client, variants ...AuthVariant) error {
+	if len(variants) == 0 {
+		variants = []AuthVariant{AnonymousVariant, MalformedVariant}
+	}
+
+	entries := Registered()
+	if len(entries) == 0 {
+		t.Skip("No Definitions are registered so there is no authentication to enforce. Register the Definitions of the API first.")
+	}
+
+	for _, e := range entries {
+		def := e.Definition
+
+		var run []AuthVariant
+		switch {
+		case def.RequiresAuth():
+			run = variants
+		case def.Authentication == AuthorizationNone:
+			run = []AuthVariant{AnonymousVariant}
+		default:
+			// Without an authentication policy there is nothing to enforce.
+			continue
+		}
+
+		if len(e.Cases) == 0 && def.RequiresAuth() {
+			t.Errorf("`%s:%s` requires authentication but has no test cases to replay", def.Method, def.Path)
+			continue
+		}
+
+		for _, tc := range e.Cases {
+			for _, v := range run {
+				if err := runAuthVariant(t, c, def, *tc, v); err != nil {
+					t.Error(err)
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func runAuthVariant(t *testing.T, c *Client, def Definition, tc TestCase, v AuthVariant) error {
+	tc.Headers = copyHeaders(tc.Headers)
+	v.Alter(&tc)
+	tc.alias = fmt.Sprintf("%s [%s credentials]", tc.alias, v.Name)
+
+	if printTestRuns {
+		fmt.Printf("Running %#v\n", tc.alias)
+	}
+
+	RR, _, err := exchange(c, def, tc)
+	if err == errNoMux {
+		t.Fatal(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	denied := RR.Code == http.StatusUnauthorized || RR.Code == http.StatusForbidden
+
+	switch {
+	case def.RequiresAuth() && !denied:
+		t.Errorf("%s: Expected statuscode 401 or 403 but received %d at `%s:%s`. The endpoint must not serve callers without valid credentials.", tc.alias, RR.Code, def.Method, tc.Path)
+	case !def.RequiresAuth() && denied:
+		t.Errorf("%s: Received statuscode %d at `%s:%s` but the Definition does not require credentials", tc.alias, RR.Code, def.Method, tc.Path)
+	}
+
+	return nil
+}
+
+// getHeader returns the value of the header using a case-insensitive match on
+// the name.
+func getHeader(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// deleteHeader removes the header using a case-insensitive match on the name.
+func deleteHeader(headers map[string]string, name string) {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package truth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  AuthVariant
+		headers  map[string]string
+		expected map[string]string
+		identity Credentials
+	}{
+		{
+			name:     "anonymous",
+			variant:  AnonymousVariant,
+			headers:  map[string]string{"authorization": "Bearer valid", "Cookie": "session=1", "Accept-Language": "en"},
+			expected: map[string]string{"Accept-Language": "en"},
+			identity: Anonymous,
+		},
+		{
+			name:     "anonymous without headers",
+			variant:  AnonymousVariant,
+			identity: Anonymous,
+		},
+		{
+			name:     "malformed keeps the scheme",
+			variant:  MalformedVariant,
+			headers:  map[string]string{"authorization": "Bearer valid", "X-API-Key": "key"},
+			expected: map[string]string{"Authorization": "Bearer " + malformed, "X-API-Key": malformed},
+			identity: Anonymous,
+		},
+		{
+			name:     "malformed without headers",
+			variant:  MalformedVariant,
+			expected: map[string]string{"Authorization": "Bearer " + malformed},
+			identity: Anonymous,
+		},
+		{
+			name:     "expired",
+			variant:  ExpiredVariant(Bearer("expired")),
+			headers:  map[string]string{"Authorization": "Bearer valid", "X-Request-Id": "1"},
+			expected: map[string]string{"X-Request-Id": "1"},
+			identity: Bearer("expired"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tc := &TestCase{Headers: test.headers}
+			test.variant.Alter(tc)
+
+			if actual, expected := JSON(tc.Headers), JSON(test.expected); string(actual) != string(expected) {
+				t.Errorf("Expected headers %s but received %s", expected, actual)
+			}
+			if tc.Identity != test.identity {
+				t.Errorf("Expected Identity %v but received %v", test.identity, tc.Identity)
+			}
+		})
+	}
+}
+
+func TestRunAuthTests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/leak":
+			rw.WriteHeader(http.StatusOK)
+		case req.URL.Path == "/denied":
+			rw.WriteHeader(http.StatusForbidden)
+		case req.Header.Get("Authorization") != "Bearer valid":
+			rw.WriteHeader(http.StatusUnauthorized)
+		default:
+			rw.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	c.SetCredentials(AuthorizationCredentials, Bearer("valid"))
+
+	tests := []struct {
+		name     string
+		def      Definition
+		cases    TestCases
+		variants []AuthVariant
+		ok       bool
+	}{
+		{
+			name:  "enforced",
+			def:   Definition{Method: "GET", Path: "/users", Authenticated: true},
+			cases: TestCases{{Name: "list", Path: "/users", Headers: map[string]string{"Authorization": "Bearer valid"}}},
+			ok:    true,
+		},
+		{
+			name:     "expired",
+			def:      Definition{Method: "GET", Path: "/users", Authenticated: true},
+			cases:    TestCases{{Name: "list", Path: "/users"}},
+			variants: []AuthVariant{ExpiredVariant(Bearer("expired"))},
+			ok:       true,
+		},
+		{
+			name:  "leaks to callers without credentials",
+			def:   Definition{Method: "GET", Path: "/leak", Authenticated: true},
+			cases: TestCases{{Name: "leak", Path: "/leak"}},
+		},
+		{
+			name:  "public endpoint denies",
+			def:   Definition{Method: "GET", Path: "/denied", Authentication: AuthorizationNone},
+			cases: TestCases{{Name: "denied", Path: "/denied"}},
+		},
+		{
+			name: "nothing to replay",
+			def:  Definition{Method: "GET", Path: "/users", Authenticated: true},
+		},
+		{
+			name:  "no policy",
+			def:   Definition{Method: "GET", Path: "/leak"},
+			cases: TestCases{{Name: "leak", Path: "/leak"}},
+			ok:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withRegistry(t)
+			test.def.MIMETypeRequest, test.def.MIMETypeResponse = MIMETypeJSON, MIMETypeJSON
+			Register(test.def, test.cases)
+
+			ok := runShared(func(t *testing.T) {
+				RunAuthTests(t, c, test.variants...)
+			})
+			if ok != test.ok {
+				t.Errorf("Expected the authentication tests to pass to be %t but received %t", test.ok, ok)
+			}
+		})
+	}
+}
+
+// withRegistry empties the registry for the test and restores it afterwards.
+func withRegistry(t *testing.T) {
+	registryMu.Lock()
+	registered := registry
+	registry = map[string]*Entry{}
+	registryMu.Unlock()
+
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = registered
+		registryMu.Unlock()
+	})
+}
@@ -13,6 +13,16 @@ import (
 type (
 	Client struct {
 		Hostname string
+		// BasePath prefixes the path of every request such as `/api/v2`.
+		BasePath string
+
+		// Credentials attached to requests keyed by the Definition's Authentication.
+		Credentials map[string]Credentials
+		// Identities are credentials a TestCase may select by name.
+		Identities map[string]Credentials
+
+		// HTTPClient sends requests. http.DefaultClient is used when nil.
+		HTTPClient *http.Client
 	}
 )
 
@@ -32,7 +42,12 @@ func (c Client) MakeRequest(md Definition, tc TestCase, result interface{}) (*ht
 		return nil, nil, err
 	}
 
-	rsp, err := http.DefaultClient.Do(req)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	rsp, err := client.Do(req)
 
 	if err != nil {
 		return nil, nil, err
@@ -68,13 +83,15 @@ func (c Client) BuildRequest(def Definition, tc TestCase) (*http.Request, error)
 		}
 	}
 
-	addr := c.Hostname + def.Path
+	path := def.Path
 
 	if tc.Path != "" {
 		// Allow the test case to override the URL
-		addr = c.Hostname + tc.Path
+		path = tc.Path
 	}
 
+	addr := c.Hostname + buildURLPath(c.BasePath, path)
+
 	if tc.Verbose {
 		fmt.Printf("%s: Building request for `%s:%s`\n", tc.Name, def.Method, addr)
 	}
@@ -103,6 +120,23 @@ func (c Client) BuildRequest(def Definition, tc TestCase) (*http.Request, error)
 		req.Header.Set(key, value)
 	}
 
+	// Credentials are attached after the headers so signatures cover the request.
+	cred, err := c.credentialsFor(def, tc)
+	if err != nil {
+		return nil, err
+	}
+	if cred != nil {
+		if err := cred.Authorize(req); err != nil {
+			return nil, fmt.Errorf("Unable to authorize request: %s", err)
+		}
+	}
+
+	// Headers provided by the test case override the credentials, such as a
+	// deliberately invalid Authorization.
+	for key, value := range tc.Headers {
+		req.Header.Set(key, value)
+	}
+
 	return req, nil
 }
 
@@ -124,7 +158,7 @@ func encode(v interface{}) (io.Reader, error) {
 		return nil, nil
 	}
 
-	b, err := json.Marshal(v)
+	b, err := marshalPayload(v)
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +166,16 @@ func encode(v interface{}) (io.Reader, error) {
 	return bytes.NewBuffer(b), nil
 }
 
+// marshalPayload serializes a payload as JSON. A []byte payload is returned verbatim
+// which allows tests to send raw or intentionally malformed bodies.
+func marshalPayload(v interface{}) ([]byte, error) {
+	if raw, ok := v.([]byte); ok {
+		return raw, nil
+	}
+
+	return json.Marshal(v)
+}
+
 // buildURLPath returns a string which concatenates a + b. The returned string will always
 // begin with a "/" and a "/" will be inserted between "a" and "b" if one was not provided on either "a" or "b".
 func buildURLPath(a, b string) string {
@@ -148,7 +192,7 @@ func buildURLPath(a, b string) string {
 		b = "/" + b
 	}
 
-	return a + a
+	return a + b
 }
 
 func copyHeaders(m map[string]string) map[string]string {
@@ -0,0 +1,40 @@
+package truth
+
+import (
+	"testing"
+)
+
+func TestBuildRequestHeaderPrecedence(t *testing.T) {
+	def := Definition{
+		Method:           "GET",
+		Path:             "/users",
+		MIMETypeRequest:  MIMETypeJSON,
+		MIMETypeResponse: MIMETypeJSON,
+		Authenticated:    true,
+	}
+
+	c := NewClient("http://localhost")
+	c.SetCredentials(AuthorizationCredentials, Bearer("valid"))
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{name: "credentials", expected: "Bearer valid"},
+		{name: "test case header wins", headers: map[string]string{"Authorization": "Bearer invalid"}, expected: "Bearer invalid"},
+		{name: "other headers keep credentials", headers: map[string]string{"X-Request-Id": "1"}, expected: "Bearer valid"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := c.BuildRequest(def, TestCase{Headers: test.headers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := req.Header.Get("Authorization"); actual != test.expected {
+				t.Errorf("Expected Authorization %q but received %q", test.expected, actual)
+			}
+		})
+	}
+}
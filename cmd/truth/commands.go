@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/aarongreenlee/truth"
+)
+
+func runCmd(args []string) int {
+	fs := newFlagSet("run", "run -host URL [-base-path path] [-snapshot snapshot.json] [-har file] contract.json|tests.yaml|dir...")
+	host := fs.String("host", "", "Base URL of the server under test such as https://staging.example.com")
+	basePath := fs.String("base-path", "", "Path prefixed to every request such as /api/v2")
+	snapshot := fs.String("snapshot", "", "Snapshot holding the Definitions file tests refer to by name")
+	har := fs.String("har", "", "Write the exchanges made by the tests to a HAR file")
+	if !parse(fs, args, 1, -1) {
+		return exitUsage
+	}
+	if *host == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var entries []truth.Entry
+	if *snapshot != "" {
+		s, err := truth.LoadSnapshot(*snapshot)
+		if err != nil {
+			return fail(err)
+		}
+		entries = s.Entries()
+	}
+
+	code := exitOK
+	client := truth.NewClient(*host)
+	client.BasePath = *basePath
+
+	if *har != "" {
+		truth.ToggleRecordExchanges()
+	}
+
+	for _, path := range fs.Args() {
+		var (
+			report fmt.Stringer
+			failed bool
+		)
+
+		if isFileTests(path) {
+			r, err := runFileTests(path, entries, client)
+			if err != nil {
+				return fail(err)
+			}
+			report, failed = r, r.Failed()
+		} else {
+			r, err := truth.VerifyContract(path, client)
+			if err != nil {
+				return fail(err)
+			}
+			report, failed = r, r.Failed()
+		}
+
+		fmt.Printf("%s: %s", path, report)
+		if failed {
+			code = exitFailed
+		}
+	}
+
+	if *har != "" {
+		if err := truth.ExportHAR(*har); err != nil {
+			return fail(err)
+		}
+	}
+
+	return code
+}
+
+// isFileTests reports if the path is a directory, a YAML file or a JSON list of
+// file tests rather than a contract.
+func isFileTests(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return true
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		b, err := ioutil.ReadFile(path)
+		return err == nil && bytes.HasPrefix(bytes.TrimSpace(b), []byte("["))
+	}
+
+	return false
+}
+
+// runFileTests runs the file tests at path.
+func runFileTests(path string, entries []truth.Entry, client *truth.Client) (*truth.FileReport, error) {
+	tests, entries, err := readFileTests(path, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return truth.VerifyFileTests(tests, entries, client)
+}
+
+// readFileTests reads the file tests at path. Without a snapshot each test is
+// bound to a JSON Definition built from its own Method and Path.
+func readFileTests(path string, entries []truth.Entry) ([]truth.FileTest, []truth.Entry, error) {
+	var (
+		tests []truth.FileTest
+		err   error
+	)
+	if info, _ := os.Stat(path); info.IsDir() {
+		tests, err = truth.LoadFileTests(path)
+	} else {
+		tests, err = truth.ReadFileTests(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if entries == nil {
+		for _, ft := range tests {
+			if ft.Method != "" && ft.Path != "" {
+				entries = append(entries, truth.Entry{Definition: truth.Definition{
+					Method:           ft.Method,
+					Path:             ft.Path,
+					MIMETypeRequest:  truth.MIMETypeJSON,
+					MIMETypeResponse: truth.MIMETypeJSON,
+				}})
+			}
+		}
+	}
+
+	return tests, entries, nil
+}
+
+func docsCmd(args []string) int {
+	fs := newFlagSet("docs", "docs [-o file] snapshot.json")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+
+	s, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	return write(*out, func(w *os.File) error { return truth.WriteMarkdown(w, s) })
+}
+
+func openAPICmd(args []string) int {
+	fs := newFlagSet("openapi", "openapi [-o file] [-title title] [-issuer URL] snapshot.json")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	title := fs.String("title", "API", "Title of the API")
+	issuer := fs.String("issuer", "", "URL of the OpenID Connect issuer of endpoints using OpenID")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+	truth.SetOpenIDIssuer(*issuer)
+
+	s, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	return write(*out, func(w *os.File) error { return truth.WriteOpenAPI(w, s, *title) })
+}
+
+func mockCmd(args []string) int {
+	fs := newFlagSet("mock", "mock [-addr addr] snapshot.json")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+
+	s, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving a mock of %d endpoints on %s\n", len(s.Endpoints), *addr)
+
+	return fail(http.ListenAndServe(*addr, truth.NewMock(s.Entries())))
+}
+
+func importCmd(args []string) int {
+	fs := newFlagSet("import", "import [-pkg name] [-o file] openapi.yaml")
+	pkg := fs.String("pkg", "definitions", "Package of the generated source")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+
+	doc, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	src, err := truth.ImportOpenAPI(doc, *pkg)
+	if err != nil {
+		return fail(err)
+	}
+
+	return write(*out, func(w *os.File) error {
+		_, err := w.Write(src)
+		return err
+	})
+}
+
+func recordCmd(args []string) int {
+	fs := newFlagSet("record", "record -target URL [-addr addr] [-snapshot snapshot.json] [-format go|yaml] [-pkg name] [-o file]")
+	target := fs.String("target", "", "Base URL of the server traffic is forwarded to such as http://localhost:8080")
+	addr := fs.String("addr", ":8081", "Address to listen on")
+	snapshot := fs.String("snapshot", "", "Snapshot holding the Definitions exchanges are matched to. Without one exchanges are grouped by method and path")
+	format := fs.String("format", "yaml", "Format of the recorded test cases: go or yaml")
+	pkg := fs.String("pkg", "definitions", "Package of Go test cases")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 0, 0) {
+		return exitUsage
+	}
+	if *target == "" || (*format != "go" && *format != "yaml") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var entries []truth.Entry
+	if *snapshot != "" {
+		s, err := truth.LoadSnapshot(*snapshot)
+		if err != nil {
+			return fail(err)
+		}
+		entries = s.Entries()
+	}
+
+	p, err := truth.NewRecordingProxy(*target, entries)
+	if err != nil {
+		return fail(err)
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Record until interrupted.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go http.Serve(l, p)
+
+	fmt.Fprintf(os.Stderr, "Recording traffic to %s on %s. Interrupt to stop.\n", *target, l.Addr())
+	<-interrupt
+	l.Close()
+
+	r := p.Recording()
+	fmt.Fprintf(os.Stderr, "Recorded %d exchanges\n", len(r.Exchanges))
+
+	return writeRecording(r, *format, *pkg, *out)
+}
+
+func harCmd(args []string) int {
+	fs := newFlagSet("har", "har [-snapshot snapshot.json] [-format go|yaml] [-pkg name] [-o file] capture.har")
+	snapshot := fs.String("snapshot", "", "Snapshot holding the Definitions exchanges are matched to. Without one exchanges are grouped by method and path")
+	format := fs.String("format", "yaml", "Format of the test cases: go or yaml")
+	pkg := fs.String("pkg", "definitions", "Package of Go test cases")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+	if *format != "go" && *format != "yaml" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	r := &truth.Recording{}
+	if *snapshot != "" {
+		s, err := truth.LoadSnapshot(*snapshot)
+		if err != nil {
+			return fail(err)
+		}
+		r.Entries = s.Entries()
+	}
+
+	exchanges, err := truth.LoadHAR(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	r.Exchanges = exchanges
+
+	return writeRecording(r, *format, *pkg, *out)
+}
+
+func postmanCmd(args []string) int {
+	fs := newFlagSet("postman", "postman [-o file] collection.json")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+
+	c, err := truth.LoadPostman(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	return write(*out, func(w *os.File) error {
+		return truth.WriteFileTests(w, c.Entries())
+	})
+}
+
+func loadCmd(args []string) int {
+	fs := newFlagSet("load", "load -host URL [-base-path path] [-snapshot snapshot.json] [-format k6|vegeta] [-o file] tests.yaml|dir...")
+	host := fs.String("host", "", "Base URL of the server under load such as https://staging.example.com")
+	basePath := fs.String("base-path", "", "Path prefixed to every request such as /api/v2")
+	snapshot := fs.String("snapshot", "", "Snapshot holding the Definitions file tests refer to by name")
+	format := fs.String("format", "k6", "Format of the load test: k6 or vegeta")
+	out := fs.String("o", "", "Write to the file instead of stdout")
+	if !parse(fs, args, 1, -1) {
+		return exitUsage
+	}
+	if *host == "" || (*format != "k6" && *format != "vegeta") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var known []truth.Entry
+	if *snapshot != "" {
+		s, err := truth.LoadSnapshot(*snapshot)
+		if err != nil {
+			return fail(err)
+		}
+		known = s.Entries()
+	}
+
+	client := truth.NewClient(*host)
+	client.BasePath = *basePath
+
+	// Group the test cases by the Definition they are bound to.
+	var entries []truth.Entry
+	index := map[string]int{}
+
+	for _, path := range fs.Args() {
+		tests, bound, err := readFileTests(path, known)
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, ft := range tests {
+			def, tc, err := ft.Bind(bound)
+			if err != nil {
+				return fail(err)
+			}
+
+			key := def.Method + ":" + def.Path
+			i, ok := index[key]
+			if !ok {
+				i = len(entries)
+				index[key] = i
+				entries = append(entries, truth.Entry{Definition: def})
+			}
+			entries[i].Cases = append(entries[i].Cases, tc)
+		}
+	}
+
+	return write(*out, func(w *os.File) error {
+		if *format == "vegeta" {
+			return truth.WriteVegeta(w, client, entries)
+		}
+		return truth.WriteK6(w, client, entries)
+	})
+}
+
+// writeRecording lists the undocumented endpoints of the recording and writes
+// its test cases in the format.
+func writeRecording(r *truth.Recording, format, pkg, out string) int {
+	for _, u := range r.Undocumented() {
+		fmt.Fprintf(os.Stderr, "Undocumented endpoint %s\n", u)
+	}
+
+	return write(out, func(w *os.File) error {
+		if format == "go" {
+			return r.WriteGo(w, pkg)
+		}
+		return r.WriteYAML(w)
+	})
+}
+
+func lintCmd(args []string) int {
+	fs := newFlagSet("lint", "lint snapshot.json")
+	if !parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+
+	s, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	issues := truth.Lint(s)
+	for _, i := range issues {
+		fmt.Println(i)
+	}
+
+	if len(issues) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func diffCmd(args []string) int {
+	fs := newFlagSet("diff", "diff [-allow-versioned] before.json after.json")
+	versioned := fs.Bool("allow-versioned", true, "Allow breaking changes when the major API version changed")
+	if !parse(fs, args, 2, 2) {
+		return exitUsage
+	}
+
+	before, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	after, err := truth.LoadSnapshot(fs.Arg(1))
+	if err != nil {
+		return fail(err)
+	}
+
+	changes := truth.DiffSnapshots(before, after)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+
+	breaking := truth.BreakingChanges(changes)
+	if len(breaking) == 0 {
+		return exitOK
+	}
+
+	if *versioned && truth.MajorVersionChanged(before, after) {
+		fmt.Printf("%d breaking changes permitted by the new major version %s\n", len(breaking), after.APIVersion)
+		return exitOK
+	}
+
+	fmt.Printf("%d breaking changes\n", len(breaking))
+	return exitFailed
+}
+
+// coverageCmd reports the endpoints of the API without test cases. The
+// endpoints come from a snapshot of every Definition, such as the baseline
+// checked by truth.CheckCompatibility, rather than from the snapshot written by
+// the tests which only holds the Definitions the tests registered.
+func coverageCmd(args []string) int {
+	fs := newFlagSet("coverage", "coverage [-min percent] api.json snapshot.json")
+	min := fs.Float64("min", 0, "Fail when fewer than this percentage of endpoints have test cases")
+	if !parse(fs, args, 2, 2) {
+		return exitUsage
+	}
+
+	api, err := truth.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	tested, err := truth.LoadSnapshot(fs.Arg(1))
+	if err != nil {
+		return fail(err)
+	}
+
+	covered := 0
+	for _, e := range api.Endpoints {
+		if tested.Cases[e.String()] > 0 {
+			covered++
+			continue
+		}
+		fmt.Printf("%s: no test cases\n", e)
+	}
+
+	percent := 100.0
+	if len(api.Endpoints) > 0 {
+		percent = float64(covered) / float64(len(api.Endpoints)) * 100
+	}
+	fmt.Printf("%d of %d endpoints have test cases (%.1f%%)\n", covered, len(api.Endpoints), percent)
+
+	if percent < *min {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// write writes to the file at path, or to stdout for an empty path.
+func write(path string, fn func(w *os.File) error) int {
+	if path == "" {
+		if err := fn(os.Stdout); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = fn(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
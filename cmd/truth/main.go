@@ -0,0 +1,134 @@
+// Command truth operates on the API snapshots and contract files written by
+// tests using the truth package so the source of truth is available outside
+// of Go.
+//
+// Usage:
+//
+//	truth <command> [flags] [arguments]
+//
+// Commands:
+//
+//	run       Verify contract files and file tests against a running server
+//	docs      Generate Markdown documentation from a snapshot
+//	openapi   Export a snapshot as an OpenAPI document
+//	mock      Serve a mock of a snapshot
+//	import    Generate Definitions and test cases from an OpenAPI document
+//	record    Record traffic through a proxy as test cases
+//	har       Convert the exchanges of a HAR file into test cases
+//	postman   Convert a Postman collection into file tests
+//	load      Export file tests as a k6 script or Vegeta targets
+//	lint      Check a snapshot for problems
+//	diff      Report the changes between two snapshots
+//	coverage  Report the endpoints of an API without test cases
+//
+// Snapshots are written by truth.WriteSnapshot and contract files by
+// truth.ExportContract. File tests are the YAML and JSON files run by
+// truth.RunFileTests.
+//
+// Exit codes are consistent across commands so CI can act on them:
+//
+//	0  Success
+//	1  A check failed: broken interactions, failed file tests, breaking changes,
+//	   lint issues or coverage below the minimum
+//	2  The command was used incorrectly
+//	3  Input could not be read or output could not be written
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+	exitError  = 3
+)
+
+type command struct {
+	summary string
+	run     func(args []string) int
+}
+
+var commands = map[string]command{
+	"run":      {"Verify contract files and file tests against a running server", runCmd},
+	"docs":     {"Generate Markdown documentation from a snapshot", docsCmd},
+	"openapi":  {"Export a snapshot as an OpenAPI document", openAPICmd},
+	"mock":     {"Serve a mock of a snapshot", mockCmd},
+	"import":   {"Generate Definitions and test cases from an OpenAPI document", importCmd},
+	"record":   {"Record traffic through a proxy as test cases", recordCmd},
+	"har":      {"Convert the exchanges of a HAR file into test cases", harCmd},
+	"postman":  {"Convert a Postman collection into file tests", postmanCmd},
+	"load":     {"Export file tests as a k6 script or Vegeta targets", loadCmd},
+	"lint":     {"Check a snapshot for problems", lintCmd},
+	"diff":     {"Report the changes between two snapshots", diffCmd},
+	"coverage": {"Report the endpoints of an API without test cases", coverageCmd},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "truth: unknown command %#v\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	return cmd.run(args[1:])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n\n\ttruth <command> [flags] [arguments]\n\nCommands:\n\n")
+
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		fmt.Fprintf(w, "\t%-10s %s\n", n, commands[n].summary)
+	}
+}
+
+// newFlagSet returns a flag set for the command which prints the usage on error.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: truth %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags and requires the number of positional arguments be
+// between min and max. A negative max allows any number.
+func parse(fs *flag.FlagSet, args []string, min, max int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+
+	if n := fs.NArg(); n < min || (max >= 0 && n > max) {
+		fs.Usage()
+		return false
+	}
+
+	return true
+}
+
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "truth: %s\n", err)
+	return exitError
+}
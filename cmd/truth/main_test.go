@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aarongreenlee/truth"
+)
+
+// execute runs the command and returns its exit code and what it wrote to
+// stdout.
+func execute(t *testing.T, args ...string) (int, string) {
+	f, err := ioutil.TempFile("", "truth-stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	stdout := os.Stdout
+	os.Stdout = f
+	code := run(args)
+	os.Stdout = stdout
+	f.Close()
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(b)
+}
+
+// writeFile writes v as JSON to a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name string, v interface{}) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, truth.JSON(v), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "truth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	users := truth.Endpoint{
+		Method:         "GET",
+		Path:           "/users",
+		Name:           "ListUsers",
+		Description:    "Lists the users.",
+		Authentication: truth.AuthorizationNone,
+	}
+	status := truth.Endpoint{Method: "GET", Path: "/status"}
+
+	before := writeFile(t, dir, "before.json", truth.Snapshot{Version: truth.SnapshotVersion, Endpoints: []truth.Endpoint{users, status}})
+	after := writeFile(t, dir, "after.json", truth.Snapshot{Version: truth.SnapshotVersion, Endpoints: []truth.Endpoint{users}, Cases: map[string]int{"GET /users": 1}})
+	major := writeFile(t, dir, "major.json", truth.Snapshot{Version: truth.SnapshotVersion, APIVersion: "2.0.0", Endpoints: []truth.Endpoint{users}})
+	clean := writeFile(t, dir, "clean.json", truth.Snapshot{Version: truth.SnapshotVersion, Endpoints: []truth.Endpoint{users}})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", truth.MIMETypeJSON)
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	interaction := func(path string) truth.Interaction {
+		return truth.Interaction{
+			Description: "status",
+			Request:     truth.InteractionRequest{Method: "GET", Path: path},
+			Response:    truth.InteractionResponse{Status: 200, Body: []byte(`{"ok":true}`)},
+		}
+	}
+	kept := writeFile(t, dir, "kept.json", truth.Contract{Version: truth.ContractVersion, Interactions: []truth.Interaction{interaction("/status")}})
+	broken := writeFile(t, dir, "broken.json", truth.Contract{Version: truth.ContractVersion, Interactions: []truth.Interaction{interaction("/health")}})
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		contains string
+	}{
+		{name: "no command", code: exitUsage},
+		{name: "help", args: []string{"help"}, code: exitUsage},
+		{name: "unknown command", args: []string{"deploy"}, code: exitUsage},
+		{name: "unknown flag", args: []string{"lint", "-fix", clean}, code: exitUsage},
+		{name: "missing argument", args: []string{"docs"}, code: exitUsage},
+		{name: "missing host", args: []string{"run", kept}, code: exitUsage},
+		{name: "unknown format", args: []string{"har", "-format", "xml", "capture.har"}, code: exitUsage},
+		{name: "missing snapshot", args: []string{"lint", filepath.Join(dir, "missing.json")}, code: exitError},
+		{name: "unwritable output", args: []string{"docs", "-o", filepath.Join(dir, "missing", "API.md"), clean}, code: exitError},
+		{name: "lint", args: []string{"lint", clean}, code: exitOK},
+		{name: "lint issues", args: []string{"lint", before}, code: exitFailed, contains: "GET /status: Name is empty"},
+		{name: "compatible", args: []string{"diff", clean, before}, code: exitOK},
+		{name: "breaking", args: []string{"diff", before, after}, code: exitFailed, contains: "1 breaking changes"},
+		{name: "major version", args: []string{"diff", before, major}, code: exitOK, contains: "permitted by the new major version 2.0.0"},
+		{name: "breaking major version", args: []string{"diff", "-allow-versioned=false", before, major}, code: exitFailed},
+		{name: "coverage", args: []string{"coverage", before, after}, code: exitOK, contains: "1 of 2 endpoints have test cases (50.0%)"},
+		{name: "coverage below minimum", args: []string{"coverage", "-min", "75", before, after}, code: exitFailed, contains: "GET /status: no test cases"},
+		{name: "docs", args: []string{"docs", clean}, code: exitOK, contains: "/users"},
+		{name: "contract kept", args: []string{"run", "-host", server.URL, kept}, code: exitOK},
+		{name: "contract broken", args: []string{"run", "-host", server.URL, broken}, code: exitFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, out := execute(t, test.args...)
+			if code != test.code {
+				t.Errorf("Expected exit code %d but received %d: %s", test.code, code, out)
+			}
+			if !strings.Contains(out, test.contains) {
+				t.Errorf("Expected the output to contain %q but received %s", test.contains, out)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "truth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.txt")
+	code := write(path, func(w *os.File) error {
+		_, err := w.WriteString("written")
+		return err
+	})
+	if code != exitOK {
+		t.Errorf("Expected exit code %d but received %d", exitOK, code)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "written" {
+		t.Errorf("Expected written but received %s", b)
+	}
+
+	// Closing the file early makes the write fail.
+	code = write(path, func(w *os.File) error { return w.Close() })
+	if code != exitError {
+		t.Errorf("Expected exit code %d when the file could not be closed but received %d", exitError, code)
+	}
+}
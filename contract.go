@@ -0,0 +1,300 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ContractVersion is the version of the contract file format written by
+// ExportContract. LoadContract rejects files of any other version.
+const ContractVersion = "truth-contract/1"
+
+type (
+	// Contract records the interactions a consumer expects from a provider. Contract
+	// files are written by the consumer's tests and replayed by the provider with
+	// VerifyContract so both sides agree without a broker.
+	Contract struct {
+		Version      string        `json:"version"`
+		Consumer     string        `json:"consumer"`
+		Provider     string        `json:"provider"`
+		Interactions []Interaction `json:"interactions"`
+	}
+
+	// Interaction is a request and the response the consumer expects. The MIME
+	// types and Authentication of the Definition let the provider rebuild the
+	// request with its own credentials.
+	Interaction struct {
+		Description      string              `json:"description"`
+		Definition       string              `json:"definition,omitempty"`
+		MIMETypeRequest  string              `json:"mimeTypeRequest,omitempty"`
+		MIMETypeResponse string              `json:"mimeTypeResponse,omitempty"`
+		Authentication   string              `json:"authentication,omitempty"`
+		Request          InteractionRequest  `json:"request"`
+		Response         InteractionResponse `json:"response"`
+	}
+
+	// InteractionRequest describes the request sent by the consumer. A JSON body
+	// is held by Body and any other body by Text. Headers carrying credentials
+	// are Redacted.
+	InteractionRequest struct {
+		Method  string            `json:"method"`
+		Path    string            `json:"path"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+		Text    string            `json:"text,omitempty"`
+	}
+
+	// InteractionResponse describes the response the consumer relies on. A body
+	// requires an exact match while Contains only requires each term be present.
+	InteractionResponse struct {
+		Status   int             `json:"status"`
+		Body     json.RawMessage `json:"body,omitempty"`
+		Text     string          `json:"text,omitempty"`
+		Contains []string        `json:"contains,omitempty"`
+	}
+
+	// ContractReport lists the interactions a provider broke.
+	ContractReport struct {
+		Verified int
+		Failures []ContractFailure
+	}
+
+	// ContractFailure describes how the provider broke an interaction.
+	ContractFailure struct {
+		Interaction Interaction
+		Problems    []string
+	}
+)
+
+// NewContract builds a contract between the consumer and provider from the
+// registered Definitions and their test cases.
+func NewContract(consumer, provider string) (*Contract, error) {
+	c := &Contract{
+		Version:  ContractVersion,
+		Consumer: consumer,
+		Provider: provider,
+	}
+
+	for _, e := range Registered() {
+		for _, tc := range e.Cases {
+			i, err := newInteraction(e.Definition, tc)
+			if err != nil {
+				return nil, err
+			}
+			c.Interactions = append(c.Interactions, i)
+		}
+	}
+
+	return c, nil
+}
+
+func newInteraction(def Definition, tc *TestCase) (Interaction, error) {
+	description := tc.Name
+	if tc.label != "" {
+		description = tc.label
+	}
+
+	i := Interaction{
+		Description:      description,
+		Definition:       def.Name,
+		MIMETypeRequest:  def.MIMETypeRequest,
+		MIMETypeResponse: def.MIMETypeResponse,
+		Authentication:   def.Authentication,
+		Request: InteractionRequest{
+			Method:  def.Method,
+			Path:    tc.Path,
+			Headers: redactHeaders(tc.Headers),
+		},
+		Response: InteractionResponse{
+			Status:   statusOf(tc),
+			Contains: tc.Contains,
+		},
+	}
+	if def.Authenticated && def.Authentication == "" {
+		i.Authentication = AuthorizationCredentials
+	}
+
+	if tc.Payload != nil {
+		body, err := marshalPayload(tc.Payload)
+		if err != nil {
+			return i, fmt.Errorf("%s: Unable to encode payload: %s", tc.alias, err)
+		}
+		i.Request.Body, i.Request.Text = splitBody(body)
+	}
+
+	if tc.ExpectBody != nil {
+		i.Response.Body, i.Response.Text = splitBody(tc.ExpectBody)
+	}
+
+	return i, nil
+}
+
+// redactHeaders returns a copy of the headers with the values of credentials
+// Redacted so contract files can be shared.
+func redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	x := redact(Exchange{RequestHeaders: h}, CredentialHeaders, nil)
+
+	out := make(map[string]string, len(headers))
+	for k := range x.RequestHeaders {
+		out[k] = x.RequestHeaders.Get(k)
+	}
+	return out
+}
+
+// splitBody returns a JSON body as raw JSON and any other body as text.
+func splitBody(b []byte) (json.RawMessage, string) {
+	if json.Valid(b) {
+		return json.RawMessage(b), ""
+	}
+	return nil, string(b)
+}
+
+// joinBody reverses splitBody.
+func joinBody(raw json.RawMessage, text string) []byte {
+	if len(raw) > 0 {
+		return raw
+	}
+	if text != "" {
+		return []byte(text)
+	}
+	return nil
+}
+
+// ExportContract writes a contract built from the registered Definitions to the
+// file at path.
+func ExportContract(path, consumer, provider string) error {
+	c, err := NewContract(consumer, provider)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.Write(f)
+}
+
+// Write serializes the contract as indented JSON.
+func (c *Contract) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// LoadContract reads a contract file.
+func LoadContract(path string) (*Contract, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Contract{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("Unable to decode contract %s: %s", path, err)
+	}
+
+	if c.Version != ContractVersion {
+		return nil, fmt.Errorf("Contract %s has version %#v but %#v is required", path, c.Version, ContractVersion)
+	}
+
+	return c, nil
+}
+
+// Verify replays every interaction against the provider and reports the ones
+// it broke. Provide a client to verify a running provider. If nil is provided
+// the server's Mux will be called directly. Redacted headers are not sent so
+// the credentials of the client authorize the requests.
+func (c *Contract) Verify(client *Client) (*ContractReport, error) {
+	report := &ContractReport{}
+
+	for _, i := range c.Interactions {
+		def := Definition{
+			Method:           i.Request.Method,
+			Path:             i.Request.Path,
+			Name:             i.Definition,
+			MIMETypeRequest:  mimeOrJSON(i.MIMETypeRequest),
+			MIMETypeResponse: mimeOrJSON(i.MIMETypeResponse),
+			Authentication:   i.Authentication,
+		}
+		tc := TestCase{
+			Name:  i.Description,
+			Path:  i.Request.Path,
+			alias: "Interaction: " + i.Description,
+		}
+		for k, v := range i.Request.Headers {
+			if v == Redacted {
+				continue
+			}
+			if tc.Headers == nil {
+				tc.Headers = map[string]string{}
+			}
+			tc.Headers[k] = v
+		}
+		if body := joinBody(i.Request.Body, i.Request.Text); body != nil {
+			tc.Payload = body
+		}
+
+		RR, body, err := exchange(client, def, tc)
+		if err != nil {
+			return report, err
+		}
+
+		problems := checkResponse(i.Response.Status, joinBody(i.Response.Body, i.Response.Text), i.Response.Contains, RR.Code, body)
+
+		if len(problems) > 0 {
+			report.Failures = append(report.Failures, ContractFailure{Interaction: i, Problems: problems})
+			continue
+		}
+
+		report.Verified++
+	}
+
+	return report, nil
+}
+
+// VerifyContract loads the contract file at path and verifies it against the
+// provider. See Contract.Verify.
+func VerifyContract(path string, client *Client) (*ContractReport, error) {
+	c, err := LoadContract(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Verify(client)
+}
+
+// Failed reports if any interaction was broken.
+func (r *ContractReport) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// String summarizes the report with a line per broken interaction.
+func (r *ContractReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d interactions verified, %d broken\n", r.Verified, len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "%s `%s:%s`\n", f.Interaction.Description, f.Interaction.Request.Method, f.Interaction.Request.Path)
+		for _, p := range f.Problems {
+			fmt.Fprintf(&b, "\t%s\n", p)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package truth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewInteraction(t *testing.T) {
+	tests := []struct {
+		name           string
+		def            Definition
+		headers        map[string]string
+		authentication string
+		expected       map[string]string
+	}{
+		{
+			name:     "public",
+			def:      Definition{Method: "GET", Path: "/status"},
+			headers:  map[string]string{"X-Request-Id": "1"},
+			expected: map[string]string{"X-Request-Id": "1"},
+		},
+		{
+			name:           "authenticated",
+			def:            Definition{Method: "GET", Path: "/users", Authenticated: true},
+			headers:        map[string]string{"Authorization": "Bearer secret", "Accept-Language": "en"},
+			authentication: AuthorizationCredentials,
+			expected:       map[string]string{"Authorization": Redacted, "Accept-Language": "en"},
+		},
+		{
+			name:           "checksum",
+			def:            Definition{Method: "POST", Path: "/payments", Authentication: AuthenticationChecksum},
+			authentication: AuthenticationChecksum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.def.MIMETypeRequest, test.def.MIMETypeResponse = MIMETypeJSON, MIMETypeJSON
+			tc := &TestCase{Name: test.name, Path: test.def.Path, Headers: test.headers}
+
+			i, err := newInteraction(test.def, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if i.Authentication != test.authentication {
+				t.Errorf("Expected Authentication %q but received %q", test.authentication, i.Authentication)
+			}
+			if i.MIMETypeRequest != MIMETypeJSON || i.MIMETypeResponse != MIMETypeJSON {
+				t.Errorf("Expected the MIME types to be recorded but received %q and %q", i.MIMETypeRequest, i.MIMETypeResponse)
+			}
+			if actual, expected := JSON(i.Request.Headers), JSON(test.expected); string(actual) != string(expected) {
+				t.Errorf("Expected headers %s but received %s", expected, actual)
+			}
+			if test.headers["Authorization"] == "Bearer secret" && tc.Headers["Authorization"] != "Bearer secret" {
+				t.Error("Expected the headers of the test case to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestNewInteractionDescription(t *testing.T) {
+	def := Definition{Method: "GET", Path: "/users"}
+	tests := []struct {
+		name     string
+		tc       *TestCase
+		expected string
+	}{
+		{name: "named", tc: &TestCase{Name: "list users"}, expected: "list users"},
+		{name: "unnamed", tc: &TestCase{}, expected: "'GET:/users' (1 of 2)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.tc.init(def, 0, 1, "users_test.go:12")
+
+			i, err := newInteraction(def, test.tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i.Description != test.expected {
+				t.Errorf("Expected description %q but received %q", test.expected, i.Description)
+			}
+		})
+	}
+}
+
+func TestContractRoundTrip(t *testing.T) {
+	c := &Contract{
+		Version:  ContractVersion,
+		Consumer: "web",
+		Provider: "api",
+		Interactions: []Interaction{{
+			Description:      "create user",
+			MIMETypeRequest:  MIMETypeJSON,
+			MIMETypeResponse: MIMETypeJSON,
+			Authentication:   AuthorizationCredentials,
+			Request:          InteractionRequest{Method: "POST", Path: "/users", Body: []byte(`{"name":"Ann"}`)},
+			Response:         InteractionResponse{Status: 201, Body: []byte(`{"id":1}`)},
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "created") {
+		t.Errorf("Expected no timestamp so contract files are stable but received:\n%s", b.String())
+	}
+
+	path := t.TempDir() + "/contract.json"
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadContract(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := JSON(loaded), JSON(c); string(actual) != string(expected) {
+		t.Errorf("Expected %s but received %s", expected, actual)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"version":"truth-contract/0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadContract(path); err == nil {
+		t.Error("Expected a contract of another version to be rejected")
+	}
+}
+
+func TestContractVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer valid" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.Header.Get("Content-Type") == "+json" {
+			rw.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		rw.Header().Set("Content-Type", MIMETypeJSON)
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte(`{"id": 1, "name": "Ann"}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	client.SetCredentials(AuthorizationCredentials, Bearer("valid"))
+
+	interaction := func(response InteractionResponse) Interaction {
+		return Interaction{
+			Description:    "create user",
+			Authentication: AuthorizationCredentials,
+			Request: InteractionRequest{
+				Method:  "POST",
+				Path:    "/users",
+				Headers: map[string]string{"Authorization": Redacted},
+				Body:    []byte(`{"name":"Ann"}`),
+			},
+			Response: response,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		response InteractionResponse
+		problems int
+	}{
+		{name: "exact body", response: InteractionResponse{Status: 201, Body: []byte(`{"name":"Ann","id":1}`)}},
+		{name: "contains", response: InteractionResponse{Status: 201, Contains: []string{`"Ann"`}}},
+		{name: "status", response: InteractionResponse{Status: 200}, problems: 1},
+		{name: "body and contains", response: InteractionResponse{Status: 201, Body: []byte(`{"id":2}`), Contains: []string{"Bob"}}, problems: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Contract{Version: ContractVersion, Interactions: []Interaction{interaction(test.response)}}
+
+			report, err := c.Verify(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			problems := 0
+			for _, f := range report.Failures {
+				problems += len(f.Problems)
+			}
+			if problems != test.problems {
+				t.Errorf("Expected %d problems but received %d:\n%s", test.problems, problems, report)
+			}
+		})
+	}
+}
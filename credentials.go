@@ -0,0 +1,188 @@
+package truth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type (
+	// Credentials attach proof of identity to a request. The Client selects
+	// credentials using the Definition's Authentication unless the TestCase
+	// chooses an Identity.
+	Credentials interface {
+		Authorize(req *http.Request) error
+	}
+
+	// Identity names credentials registered with SetIdentity. Use an Identity
+	// to test the same Definition as different users:
+	//
+	//	truth.SetIdentity("admin", truth.Bearer(adminToken))
+	//
+	//	tc := truth.TestCase{
+	//		Identity: truth.Identity("admin"),
+	//	}
+	Identity string
+
+	// Bearer authorizes requests with a bearer token.
+	Bearer string
+
+	// BasicAuth authorizes requests with HTTP basic authentication.
+	BasicAuth struct {
+		Username string
+		Password string
+	}
+
+	// APIKey authorizes requests with a key sent as a header or, when InQuery is
+	// true, as a query string parameter.
+	APIKey struct {
+		Name    string
+		Value   string
+		InQuery bool
+	}
+
+	// SessionCookie authorizes requests with a session cookie.
+	SessionCookie struct {
+		Name  string
+		Value string
+	}
+
+	anonymous struct{}
+)
+
+// Anonymous sends no credentials. A TestCase using the Anonymous Identity will
+// not receive the Client's default credentials.
+var Anonymous Credentials = anonymous{}
+
+var errUnresolvedIdentity = errors.New("An Identity must be resolved by a Client before it can authorize a request")
+
+// Authorize always fails. The Client resolves an Identity to the credentials
+// registered under its name.
+func (id Identity) Authorize(req *http.Request) error {
+	return errUnresolvedIdentity
+}
+
+// Authorize sets the `Authorization` header.
+func (token Bearer) Authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(token))
+	return nil
+}
+
+// Authorize sets the `Authorization` header.
+func (b BasicAuth) Authorize(req *http.Request) error {
+	req.SetBasicAuth(b.Username, b.Password)
+	return nil
+}
+
+// Authorize sets the header or query string parameter.
+func (k APIKey) Authorize(req *http.Request) error {
+	if k.Name == "" {
+		return errors.New("APIKey requires a Name")
+	}
+
+	if !k.InQuery {
+		req.Header.Set(k.Name, k.Value)
+		return nil
+	}
+
+	q := req.URL.Query()
+	q.Set(k.Name, k.Value)
+	req.URL.RawQuery = q.Encode()
+
+	return nil
+}
+
+// Authorize adds the cookie.
+func (s SessionCookie) Authorize(req *http.Request) error {
+	req.AddCookie(&http.Cookie{Name: s.Name, Value: s.Value})
+	return nil
+}
+
+func (anonymous) Authorize(req *http.Request) error {
+	return nil
+}
+
+// SetCredentials registers the credentials the Client attaches to requests for
+// Definitions using the provided Authentication such as AuthorizationCredentials.
+func (c *Client) SetCredentials(authentication string, cred Credentials) {
+	if c.Credentials == nil {
+		c.Credentials = map[string]Credentials{}
+	}
+	c.Credentials[authentication] = cred
+}
+
+// SetIdentity registers credentials a TestCase may select by name.
+func (c *Client) SetIdentity(name string, cred Credentials) {
+	if c.Identities == nil {
+		c.Identities = map[string]Credentials{}
+	}
+	c.Identities[name] = cred
+}
+
+// SetCredentials registers credentials used by in-process tests. See
+// Client.SetCredentials.
+func SetCredentials(authentication string, cred Credentials) {
+	integrationClient.SetCredentials(authentication, cred)
+}
+
+// SetIdentity registers an identity used by in-process tests. See
+// Client.SetIdentity.
+func SetIdentity(name string, cred Credentials) {
+	integrationClient.SetIdentity(name, cred)
+}
+
+// credentialsFor selects the credentials for a request. The TestCase's Identity
+// takes precedence over the credentials registered for the Definition's
+// Authentication. Nil is returned when no credentials apply.
+func (c Client) credentialsFor(def Definition, tc TestCase) (Credentials, error) {
+	if name, ok := tc.Identity.(Identity); ok {
+		cred, ok := c.Identities[string(name)]
+		if !ok {
+			return nil, fmt.Errorf("Identity %#v is not registered", string(name))
+		}
+		return cred, nil
+	}
+
+	if tc.Identity != nil {
+		return tc.Identity, nil
+	}
+
+	if !def.RequiresAuth() {
+		return nil, nil
+	}
+
+	key := def.Authentication
+	if key == "" {
+		key = AuthorizationCredentials
+	}
+
+	return c.Credentials[key], nil
+}
+
+// headerCredentials returns the names of the headers holding the API keys
+// registered with the Client.
+func (c Client) headerCredentials() []string {
+	var names []string
+	for _, creds := range []map[string]Credentials{c.Credentials, c.Identities} {
+		for _, cred := range creds {
+			if k, ok := cred.(APIKey); ok && !k.InQuery {
+				names = append(names, k.Name)
+			}
+		}
+	}
+	return names
+}
+
+// queryCredentials returns the names of the query string parameters holding
+// the credentials and identities registered with the Client.
+func (c Client) queryCredentials() []string {
+	var names []string
+	for _, creds := range []map[string]Credentials{c.Credentials, c.Identities} {
+		for _, cred := range creds {
+			if k, ok := cred.(APIKey); ok && k.InQuery {
+				names = append(names, k.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,106 @@
+package truth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCredentialsAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		cred     Credentials
+		header   string
+		expected string
+		url      string
+		invalid  bool
+	}{
+		{name: "bearer", cred: Bearer("token"), header: "Authorization", expected: "Bearer token", url: "http://localhost/users?page=2"},
+		{name: "basic", cred: BasicAuth{Username: "ann", Password: "secret"}, header: "Authorization", expected: "Basic YW5uOnNlY3JldA==", url: "http://localhost/users?page=2"},
+		{name: "api key header", cred: APIKey{Name: "X-API-Key", Value: "key"}, header: "X-API-Key", expected: "key", url: "http://localhost/users?page=2"},
+		{name: "api key in query", cred: APIKey{Name: "api_key", Value: "a b", InQuery: true}, url: "http://localhost/users?api_key=a+b&page=2"},
+		{name: "api key without name", cred: APIKey{Value: "key"}, invalid: true},
+		{name: "session cookie", cred: SessionCookie{Name: "session", Value: "abc"}, header: "Cookie", expected: "session=abc", url: "http://localhost/users?page=2"},
+		{name: "anonymous", cred: Anonymous, header: "Authorization", url: "http://localhost/users?page=2"},
+		{name: "unresolved identity", cred: Identity("admin"), invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://localhost/users?page=2", nil)
+
+			err := test.cred.Authorize(req)
+			if test.invalid {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.header != "" && req.Header.Get(test.header) != test.expected {
+				t.Errorf("Expected %s %q but received %q", test.header, test.expected, req.Header.Get(test.header))
+			}
+			if actual := req.URL.String(); actual != test.url {
+				t.Errorf("Expected %s but received %s", test.url, actual)
+			}
+		})
+	}
+}
+
+func TestCredentialsFor(t *testing.T) {
+	c := NewClient("http://localhost")
+	c.SetCredentials(AuthorizationCredentials, Bearer("default"))
+	c.SetCredentials(AuthorizationOpenID, Bearer("openid"))
+	c.SetIdentity("admin", Bearer("admin"))
+
+	tests := []struct {
+		name     string
+		def      Definition
+		identity Credentials
+		expected Credentials
+		invalid  bool
+	}{
+		{name: "public", def: Definition{}},
+		{name: "default authentication", def: Definition{Authenticated: true}, expected: Bearer("default")},
+		{name: "authentication", def: Definition{Authentication: AuthorizationOpenID}, expected: Bearer("openid")},
+		{name: "unregistered authentication", def: Definition{Authentication: AuthenticationChecksum}},
+		{name: "identity", def: Definition{Authenticated: true}, identity: Identity("admin"), expected: Bearer("admin")},
+		{name: "identity on a public definition", def: Definition{}, identity: Identity("admin"), expected: Bearer("admin")},
+		{name: "unregistered identity", def: Definition{Authenticated: true}, identity: Identity("owner"), invalid: true},
+		{name: "credentials", def: Definition{Authenticated: true}, identity: Bearer("other"), expected: Bearer("other")},
+		{name: "anonymous", def: Definition{Authenticated: true}, identity: Anonymous, expected: Anonymous},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cred, err := c.credentialsFor(test.def, TestCase{Identity: test.identity})
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Expected an error but received %v", cred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cred != test.expected {
+				t.Errorf("Expected %v but received %v", test.expected, cred)
+			}
+		})
+	}
+}
+
+func TestBuildRequestAnonymous(t *testing.T) {
+	c := NewClient("http://localhost")
+	c.SetCredentials(AuthorizationCredentials, Bearer("valid"))
+
+	req, err := c.BuildRequest(Definition{Method: "GET", Path: "/users", Authenticated: true}, TestCase{Identity: Anonymous})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := req.Header.Get("Authorization"); actual != "" {
+		t.Errorf("Expected no credentials but received %q", actual)
+	}
+}
@@ -1,8 +1,11 @@
 package truth
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 )
 
 const (
@@ -57,6 +60,8 @@ type (
 
 		Authenticated  bool
 		Authentication string
+		// Access maps roles to the status code each receives. See RunAccessTests.
+		Access AccessPolicy
 
 		// Attributes for documentation and logging.
 
@@ -101,6 +106,43 @@ func (def *Definition) Init() error {
 	return nil
 }
 
+// RequiresAuth reports if the Definition demands callers supply credentials.
+func (def Definition) RequiresAuth() bool {
+	if def.Authentication == AuthorizationNone {
+		return false
+	}
+
+	return def.Authenticated || def.Authentication != ""
+}
+
+// Validate reports if the body does not decode into the data structure which
+// defines it. Fields unknown to the data structure are considered a violation.
+// If the definition holds no data structure every body is considered valid.
+// Data holding a *Schema validates the body against the schema.
+func (b BodyDefinition) Validate(body []byte) error {
+	if b.Data == nil {
+		return nil
+	}
+
+	if s, ok := b.Data.(*Schema); ok {
+		return s.Validate(body)
+	}
+
+	t := reflect.TypeOf(b.Data)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(reflect.New(t).Interface()); err != nil {
+		return fmt.Errorf("Body does not match %s: %s", t, err)
+	}
+
+	return nil
+}
+
 // Configure returns a new Metadata struct initialized to default values unless
 // customized by passing optional functions.
 func Configure(d Definition, options ...func(*Definition)) Definition {
@@ -125,6 +167,18 @@ func UsingCredentials(d *Definition) {
 	d.Authentication = AuthorizationCredentials
 }
 
+// UsingChecksum specifies that the provided definition requires requests
+// be signed. See Signer.
+func UsingChecksum(d *Definition) {
+	d.Authentication = AuthenticationChecksum
+}
+
+// UsingOpenID specifies that the provided definition requires OpenID
+// credentials. See OpenIDProvider.
+func UsingOpenID(d *Definition) {
+	d.Authentication = AuthorizationOpenID
+}
+
 // ResourceMIMEType builds a custom mimetype such as
 //	application/vnd.{your-namespace}.user
 // using the provided class. The formula is:
@@ -0,0 +1,162 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefinitionsVersion is the version of the format written by
+// MarshalDefinitions. UnmarshalDefinitions rejects any other version.
+const DefinitionsVersion = "truth-definitions/1"
+
+const (
+	// FormatJSON serializes Definitions as JSON.
+	FormatJSON = "json"
+	// FormatYAML serializes Definitions as YAML.
+	FormatYAML = "yaml"
+)
+
+type (
+	// DefinitionSet is the Definitions of a service as data so they can be
+	// published and consumed without importing the service's Go packages. Go
+	// values describing parameters and bodies are replaced by their reflected
+	// schemas.
+	//
+	// A consumer loads the set and uses the Definitions as its own:
+	//
+	//	set, err := truth.LoadDefinitions("testdata/users.definitions.yaml")
+	//	...
+	//	for _, def := range set.Definitions() {
+	//		truth.Register(def, nil)
+	//	}
+	DefinitionSet struct {
+		Version    string     `json:"version" yaml:"version"`
+		Service    string     `json:"service,omitempty" yaml:"service,omitempty"`
+		APIVersion string     `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+		Endpoints  []Endpoint `json:"endpoints" yaml:"endpoints"`
+	}
+)
+
+// NewDefinitionSet returns the set of the Definitions.
+func NewDefinitionSet(service, apiVersion string, defs ...Definition) DefinitionSet {
+	s := DefinitionSet{
+		Version:    DefinitionsVersion,
+		Service:    service,
+		APIVersion: apiVersion,
+		Endpoints:  make([]Endpoint, len(defs)),
+	}
+
+	for i, def := range defs {
+		s.Endpoints[i] = NewEndpoint(def)
+	}
+
+	return s
+}
+
+// RegisteredDefinitions returns the set of every registered Definition.
+func RegisteredDefinitions(service, apiVersion string) DefinitionSet {
+	var defs []Definition
+	for _, e := range Registered() {
+		defs = append(defs, e.Definition)
+	}
+
+	return NewDefinitionSet(service, apiVersion, defs...)
+}
+
+// Definitions returns the Definitions of the set. Schemas take the place of the
+// Go values holding parameters and bodies.
+func (s DefinitionSet) Definitions() []Definition {
+	defs := make([]Definition, len(s.Endpoints))
+	for i, e := range s.Endpoints {
+		defs[i] = e.Definition()
+	}
+	return defs
+}
+
+// Entries returns an Entry without test cases for every Definition of the set.
+func (s DefinitionSet) Entries() []Entry {
+	return Snapshot{Endpoints: s.Endpoints}.Entries()
+}
+
+// MarshalDefinitions serializes the set in the format, FormatJSON or FormatYAML.
+func MarshalDefinitions(s DefinitionSet, format string) ([]byte, error) {
+	if s.Version == "" {
+		s.Version = DefinitionsVersion
+	}
+
+	switch format {
+	case FormatJSON:
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(s)
+	}
+
+	return nil, fmt.Errorf("Unknown format %#v", format)
+}
+
+// UnmarshalDefinitions reads a set serialized as JSON or YAML.
+func UnmarshalDefinitions(b []byte) (DefinitionSet, error) {
+	var (
+		s   DefinitionSet
+		err error
+	)
+
+	if json.Valid(b) {
+		err = json.Unmarshal(b, &s)
+	} else {
+		err = yaml.Unmarshal(b, &s)
+	}
+	if err != nil {
+		return s, fmt.Errorf("Unable to decode Definitions: %s", err)
+	}
+
+	if s.Version != DefinitionsVersion {
+		return s, fmt.Errorf("Definitions have version %#v but %#v is required", s.Version, DefinitionsVersion)
+	}
+
+	return s, nil
+}
+
+// WriteDefinitions writes every registered Definition to the file at path. A
+// `.yaml` or `.yml` extension selects YAML and anything else JSON.
+func WriteDefinitions(path, service, apiVersion string) error {
+	b, err := MarshalDefinitions(RegisteredDefinitions(service, apiVersion), formatOf(path))
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// LoadDefinitions reads a set written by WriteDefinitions.
+func LoadDefinitions(path string) (DefinitionSet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return DefinitionSet{}, err
+	}
+
+	s, err := UnmarshalDefinitions(b)
+	if err != nil {
+		return s, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return s, nil
+}
+
+// formatOf returns the format selected by the extension of the path.
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
@@ -0,0 +1,40 @@
+package truth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalDefinitions(t *testing.T) {
+	set := NewDefinitionSet("users", "1.0.0", Definition{
+		Method:           "GET",
+		Path:             "/users/{id}",
+		Name:             "Get User",
+		MIMETypeResponse: MIMETypeJSON,
+		ResponseBody:     BodyDefinition{Data: schemaUser{}},
+	})
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			b, err := MarshalDefinitions(set, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), "cases") {
+				t.Errorf("Expected the published Definitions to hold no test case counts but received:\n%s", b)
+			}
+
+			loaded, err := UnmarshalDefinitions(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := JSON(loaded), JSON(set); string(actual) != string(expected) {
+				t.Errorf("Expected %s but received %s", expected, actual)
+			}
+		})
+	}
+
+	if _, err := UnmarshalDefinitions([]byte(`{"version":"truth-definitions/0"}`)); err == nil {
+		t.Error("Expected Definitions of another version to be rejected")
+	}
+}
@@ -0,0 +1,295 @@
+package truth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type (
+	// Target is a server a DiffRunner sends test cases to. A Handler is called
+	// in-process and a Client is called over the wire. Without either the mux
+	// under test is called in-process.
+	Target struct {
+		Name    string
+		Handler http.Handler
+		Client  *Client
+	}
+
+	// DiffRunner sends every test case to two targets, such as the old and new
+	// implementation of a handler, and reports where their responses diverge.
+	// Status codes are always compared.
+	//
+	//	d := &truth.DiffRunner{
+	//		A:      truth.Target{Name: "legacy", Handler: legacyMux},
+	//		B:      truth.Target{Name: "rewrite", Handler: newMux},
+	//		Ignore: []string{"id", "created", "items.*.etag"},
+	//	}
+	//	truth.RunDiffTests(t, d, getUsersDef, getUsersCases)
+	//	fmt.Print(d.Report())
+	DiffRunner struct {
+		A, B Target
+
+		// Headers lists the headers compared. Content-Type is compared when none
+		// are listed.
+		Headers []string
+		// Ignore lists volatile fields of JSON bodies which are not compared. Fields
+		// are dotted paths where `*` matches any key or array index.
+		Ignore []string
+
+		mu     sync.Mutex
+		report DiffReport
+	}
+
+	// DiffReport lists the test cases whose responses diverged.
+	DiffReport struct {
+		Compared    int
+		Divergences []Divergence
+	}
+
+	// Divergence describes how the responses of the targets to a test case differ.
+	Divergence struct {
+		Definition  Definition
+		Case        string
+		Differences []Difference
+	}
+)
+
+// RunDiffTests runs every test case with the DiffRunner.
+func RunDiffTests(t *testing.T, d *DiffRunner, def Definition, cases TestCases) {
+	cases.init(def, getCaller(2))
+	Register(def, cases)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if err := d.Run(t, def, *tc); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Run sends the test case to both targets and fails the test when their
+// responses diverge. Run is a Runner. A test case which is not selected by the
+// `-truth.tags` flag skips the test.
+func (d *DiffRunner) Run(t *testing.T, def Definition, tc TestCase) error {
+	if !selected(tc) {
+		t.Skipf("%s is not selected by its tags", tc.alias)
+	}
+
+	if err := preflight(def, tc.Path); err != nil {
+		return fmt.Errorf("%s: Preflight failed: %s", tc.alias, err.Error())
+	}
+
+	diffs, err := d.Diff(def, tc)
+	if err == errNoMux {
+		t.Fatal(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, diff := range diffs {
+		t.Errorf("%s: %s (A) and %s (B) diverge at `%s:%s`: %s", tc.alias, d.A.name("A"), d.B.name("B"), def.Method, tc.Path, diff)
+	}
+
+	return nil
+}
+
+// Diff sends the test case to both targets, records the result in the report
+// and returns the differences between the responses.
+func (d *DiffRunner) Diff(def Definition, tc TestCase) ([]Difference, error) {
+	aRR, aBody, err := d.A.exchange(def, tc)
+	if err != nil {
+		return nil, err
+	}
+	bRR, bBody, err := d.B.exchange(def, tc)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := d.Headers
+	if len(headers) == 0 {
+		headers = []string{"Content-Type"}
+	}
+	diffs := compareResponses(aRR, aBody, bRR, bBody, comparison{headers: headers, fields: d.Ignore})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.report.Compared++
+	if len(diffs) > 0 {
+		d.report.Divergences = append(d.report.Divergences, Divergence{Definition: def, Case: tc.Name, Differences: diffs})
+	}
+
+	return diffs, nil
+}
+
+// Report returns the divergences found so far.
+func (d *DiffRunner) Report() *DiffReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r := d.report
+	r.Divergences = append([]Divergence(nil), r.Divergences...)
+	return &r
+}
+
+func (target Target) name(fallback string) string {
+	switch {
+	case target.Name != "":
+		return target.Name
+	case target.Client != nil:
+		return target.Client.Hostname
+	}
+	return fallback
+}
+
+func (target Target) exchange(def Definition, tc TestCase) (*httptest.ResponseRecorder, []byte, error) {
+	switch {
+	case target.Client != nil:
+		return exchange(target.Client, def, tc)
+	case target.Handler != nil:
+		return serveHandler(target.Handler, def, tc)
+	}
+	return serveInProcess(def, tc)
+}
+
+// missingField describes a field of a JSON object which is not present.
+const missingField = "(missing)"
+
+// removeJSON removes the field at the path from the decoded JSON.
+func removeJSON(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return v
+	}
+
+	key, rest := path[0], path[1:]
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+				continue
+			}
+			v[k] = removeJSON(v[k], rest)
+		}
+	case []interface{}:
+		for i := range v {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = nil
+				continue
+			}
+			v[i] = removeJSON(v[i], rest)
+		}
+	}
+
+	return v
+}
+
+// diffJSON returns a difference for every path where the decoded JSON values
+// differ. A field which is null differs from a field which is missing.
+func diffJSON(path string, a, b interface{}) []Difference {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]bool{}
+		for k := range av {
+			keys[k] = true
+		}
+		for k := range bv {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		var diffs []Difference
+		for _, k := range sorted {
+			a, aok := av[k]
+			b, bok := bv[k]
+			switch {
+			case !aok:
+				diffs = append(diffs, Difference{Field: path + "." + k, A: missingField, B: string(JSON(b))})
+			case !bok:
+				diffs = append(diffs, Difference{Field: path + "." + k, A: string(JSON(a)), B: missingField})
+			default:
+				diffs = append(diffs, diffJSON(path+"."+k, a, b)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+
+		var diffs []Difference
+		for i := range av {
+			diffs = append(diffs, diffJSON(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i])...)
+		}
+		return diffs
+	}
+
+	aj, bj := JSON(a), JSON(b)
+	if string(aj) == string(bj) {
+		return nil
+	}
+	return []Difference{{Field: path, A: string(aj), B: string(bj)}}
+}
+
+// Failed reports if any test case diverged.
+func (r *DiffReport) Failed() bool {
+	return len(r.Divergences) > 0
+}
+
+// String lists the divergences grouped by Definition.
+func (r *DiffReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d test cases compared, %d diverged\n", r.Compared, len(r.Divergences))
+
+	groups := map[string][]Divergence{}
+	var keys []string
+	for _, d := range r.Divergences {
+		k := fmt.Sprintf("%s:%s", d.Definition.Method, d.Definition.Path)
+		if d.Definition.Name != "" {
+			k += " " + d.Definition.Name
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], d)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s\n", k)
+		for _, d := range groups[k] {
+			fmt.Fprintf(&b, "\t%s\n", d.Case)
+			for _, diff := range d.Differences {
+				fmt.Fprintf(&b, "\t\t%s: %s != %s\n", diff.Field, diff.A, diff.B)
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package truth
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected []Difference
+	}{
+		{name: "equal", a: `{"id":1,"tags":["a"]}`, b: `{"tags":["a"],"id":1}`},
+		{name: "changed value", a: `{"id":1}`, b: `{"id":2}`, expected: []Difference{{Field: "body.id", A: "1", B: "2"}}},
+		{name: "null and missing", a: `{"id":1,"email":null}`, b: `{"id":1}`, expected: []Difference{{Field: "body.email", A: "null", B: missingField}}},
+		{name: "missing and null", a: `{}`, b: `{"email":null}`, expected: []Difference{{Field: "body.email", A: missingField, B: "null"}}},
+		{name: "nested", a: `{"user":{"name":"Ann"}}`, b: `{"user":{"name":"Bob"}}`, expected: []Difference{{Field: "body.user.name", A: `"Ann"`, B: `"Bob"`}}},
+		{name: "array element", a: `[1,2]`, b: `[1,3]`, expected: []Difference{{Field: "body[1]", A: "2", B: "3"}}},
+		{name: "array length", a: `[1]`, b: `[1,2]`, expected: []Difference{{Field: "body", A: "[1]", B: "[1,2]"}}},
+		{name: "type", a: `{"id":1}`, b: `{"id":"1"}`, expected: []Difference{{Field: "body.id", A: "1", B: `"1"`}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var a, b interface{}
+			if err := json.Unmarshal([]byte(test.a), &a); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.b), &b); err != nil {
+				t.Fatal(err)
+			}
+
+			if actual, expected := JSON(diffJSON("body", a, b)), JSON(test.expected); string(actual) != string(expected) {
+				t.Errorf("Expected %s but received %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRemoveJSON(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "id", expected: `{"items":[{"etag":"a","id":1},{"etag":"b","id":2}]}`},
+		{path: "items.*.etag", expected: `{"id":7,"items":[{"id":1},{"id":2}]}`},
+		{path: "items.1", expected: `{"id":7,"items":[{"etag":"a","id":1},null]}`},
+		{path: "missing.field", expected: `{"id":7,"items":[{"etag":"a","id":1},{"etag":"b","id":2}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			var v interface{}
+			json.Unmarshal([]byte(`{"id":7,"items":[{"id":1,"etag":"a"},{"id":2,"etag":"b"}]}`), &v)
+
+			if actual := string(JSON(removeJSON(v, strings.Split(test.path, ".")))); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDiffRunner(t *testing.T) {
+	handler := func(body string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", MIMETypeJSON)
+			io.WriteString(rw, body)
+		})
+	}
+
+	d := &DiffRunner{
+		A:      Target{Name: "legacy", Handler: handler(`{"id":1,"name":"Ann","etag":"a"}`)},
+		B:      Target{Name: "rewrite", Handler: handler(`{"id":1,"name":null,"etag":"b"}`)},
+		Ignore: []string{"etag"},
+	}
+	def := Definition{Method: "GET", Path: "/users/1", MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON}
+
+	diffs, err := d.Diff(def, TestCase{Name: "get user", Path: "/users/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := JSON(diffs), JSON([]Difference{{Field: "body.name", A: `"Ann"`, B: "null"}}); string(actual) != string(expected) {
+		t.Errorf("Expected %s but received %s", expected, actual)
+	}
+
+	report := d.Report()
+	if report.Compared != 1 || !report.Failed() {
+		t.Errorf("Expected one diverging test case but received:\n%s", report)
+	}
+}
@@ -0,0 +1,205 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WriteMarkdown writes documentation for every endpoint of the snapshot as
+// Markdown. Endpoints are grouped by Package and ordered by path. The example
+// requests send placeholders where the credentials registered for in-process
+// tests are sent.
+func WriteMarkdown(w io.Writer, s Snapshot) error {
+	groups := map[string][]Endpoint{}
+	for _, e := range s.Endpoints {
+		groups[e.Package] = append(groups[e.Package], e)
+	}
+
+	packages := make([]string, 0, len(groups))
+	for p := range groups {
+		packages = append(packages, p)
+	}
+	sort.Strings(packages)
+
+	b := &strings.Builder{}
+
+	b.WriteString("# API Reference\n")
+	if s.APIVersion != "" {
+		fmt.Fprintf(b, "\nVersion %s\n", s.APIVersion)
+	}
+
+	for _, p := range packages {
+		if p != "" {
+			fmt.Fprintf(b, "\n## %s\n", p)
+		}
+		for _, e := range groups[p] {
+			writeEndpointMarkdown(b, e, integrationClient.Credentials)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeEndpointMarkdown(b *strings.Builder, e Endpoint, creds map[string]Credentials) {
+	title := e.Name
+	if title == "" {
+		title = e.String()
+	}
+
+	fmt.Fprintf(b, "\n### %s\n\n", title)
+	fmt.Fprintf(b, "`%s %s`\n", e.Method, e.Path)
+
+	if e.Description != "" {
+		fmt.Fprintf(b, "\n%s\n", dedent(e.Description))
+	}
+
+	if e.requiresAuth() {
+		auth := e.Authentication
+		if auth == "" {
+			auth = AuthorizationCredentials
+		}
+		fmt.Fprintf(b, "\n**Authentication:** %s\n", auth)
+	}
+
+	if len(e.Params) > 0 {
+		b.WriteString("\n**Path parameters:** ")
+		for i, p := range e.Params {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "`%s`", p)
+		}
+		b.WriteString("\n")
+	}
+
+	writeSchemaFields(b, "Query parameters", e.QueryParams)
+	writeHeadersMarkdown(b, "Request headers", e.RequestHeaders)
+
+	if e.RequestBody != nil {
+		fmt.Fprintf(b, "\n**Request body** (%s):\n\n", mimeOrJSON(e.MIMETypeRequest))
+		writeExampleMarkdown(b, e.RequestBody)
+	}
+
+	writeHeadersMarkdown(b, "Response headers", e.ResponseHeaders)
+
+	if e.ResponseBody != nil {
+		fmt.Fprintf(b, "\n**Response body** (%s):\n\n", mimeOrJSON(e.MIMETypeResponse))
+		writeExampleMarkdown(b, e.ResponseBody)
+	}
+
+	fmt.Fprintf(b, "\n**Example:**\n\n```sh\n%s\n```\n", endpointSnippet(e, creds).curl())
+}
+
+// endpointSnippet describes a request to the endpoint. Path parameters are left
+// as they are and placeholders take the place of credentials, sent where the
+// credentials registered for the Authentication of the endpoint send them.
+func endpointSnippet(e Endpoint, creds map[string]Credentials) snippet {
+	s := snippet{method: e.Method, url: "http://localhost" + e.Path, header: http.Header{}}
+
+	for k, v := range e.RequestHeaders {
+		s.header.Set(k, v)
+	}
+
+	if e.requiresAuth() {
+		auth := e.Authentication
+		if auth == "" {
+			auth = AuthorizationCredentials
+		}
+
+		switch cred := creds[auth].(type) {
+		case APIKey:
+			if cred.InQuery {
+				s.url += "?" + url.QueryEscape(cred.Name) + "=<credentials>"
+			} else {
+				s.header.Set(cred.Name, "<credentials>")
+			}
+		case SessionCookie:
+			s.header.Set("Cookie", cred.Name+"=<credentials>")
+		default:
+			if auth == AuthenticationChecksum {
+				s.header.Set(SignatureHeader, "<signature>")
+			} else {
+				s.header.Set("Authorization", "<credentials>")
+			}
+		}
+	}
+
+	if e.RequestBody != nil {
+		if body, err := json.Marshal(e.RequestBody.Example()); err == nil {
+			s.body = body
+			s.header.Set("Content-Type", mimeOrJSON(e.MIMETypeRequest))
+		}
+	}
+
+	return s
+}
+
+func writeSchemaFields(b *strings.Builder, title string, s *Schema) {
+	if s == nil || len(s.Properties) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for n := range s.Properties {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "\n**%s:**\n\n", title)
+	b.WriteString("| Name | Type | Required |\n|---|---|---|\n")
+	for _, n := range names {
+		required := ""
+		if s.requires(n) {
+			required = "yes"
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s |\n", n, s.Properties[n].typeName(), required)
+	}
+}
+
+func writeHeadersMarkdown(b *strings.Builder, title string, headers map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(headers))
+	for n := range headers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "\n**%s:**\n\n", title)
+	for _, n := range names {
+		fmt.Fprintf(b, "- `%s: %s`\n", n, headers[n])
+	}
+}
+
+func writeExampleMarkdown(b *strings.Builder, s *Schema) {
+	example, err := json.MarshalIndent(s.Example(), "", "  ")
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(b, "```json\n%s\n```\n", example)
+}
+
+func mimeOrJSON(mime string) string {
+	if mime == "" {
+		return MIMETypeJSON
+	}
+	return mime
+}
+
+// dedent removes the indentation Go source adds to the lines of multi-line
+// descriptions.
+func dedent(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 )
 
@@ -19,7 +20,12 @@ func main() {
 	// without actually going over the wire.
 	bootstrap()
 
-	http.ListenAndServe(":65432", router)
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "65432"
+	}
+
+	http.ListenAndServe(":"+port, router)
 }
 
 var router Multiplexer
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 )
 
 // To perform integration tests without actually going out to the network the server
@@ -20,7 +21,13 @@ func main() {
 	// without actually going over the wire.
 	configure()
 
-	http.ListenAndServe(":65432", mux)
+	// The port may be chosen by the environment such as the truth.Server harness.
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "65432"
+	}
+
+	http.ListenAndServe(":"+port, mux)
 }
 
 // Because we separated configuration from mounting/listening we can test
@@ -6,6 +6,14 @@ import (
 	"testing"
 )
 
+// helloWorld defines our handler under test. The name lets test cases kept in
+// files refer to it.
+var helloWorld = truth.Definition{
+	Name:   "Hello World",
+	Method: "GET",
+	Path:   "/helloworld",
+}
+
 // SetupTest sets up the application under test. Any dependencies should be loaded by this
 // "bootstrap" call and the entire application under test should be ready with the
 // exception of actually listening on a port. We won't need to listen on a port
@@ -20,10 +28,7 @@ func TestHelloWorld(t *testing.T) {
 	SetupTest()
 
 	// Define our handler under test.
-	def := truth.Definition{
-		Method: "GET",
-		Path:   "/helloworld",
-	}
+	def := helloWorld
 
 	tests := truth.TestCases{
 		// This is a simple example. We name the test, verify the
@@ -90,3 +95,45 @@ func TestHelloWorld(t *testing.T) {
 	// Run the tests!
 	truth.RunIntegrationTests(t, def, tests, nil)
 }
+
+// FuzzHelloWorld seeds a fuzz target from our test cases. `go test` runs the seed
+// corpus while `go test -fuzz FuzzHelloWorld` mutates the requests until the
+// handler panics, fails with a 5XX status code or returns an invalid body.
+func FuzzHelloWorld(f *testing.F) {
+	SetupTest()
+
+	def := truth.Definition{
+		Method: "GET",
+		Path:   "/helloworld",
+	}
+
+	truth.Fuzz(f, def, truth.TestCases{
+		{Path: "/helloworld?abc"},
+	})
+}
+
+// TestHelloWorldFiles runs the test cases our QA team keeps in YAML and JSON
+// files under `testdata`. The files find their Definitions in the registry so
+// we register the Definition first.
+func TestHelloWorldFiles(t *testing.T) {
+	SetupTest()
+
+	truth.Register(helloWorld, nil)
+
+	truth.RunFileTests(t, "testdata", nil)
+}
+
+// TestHelloWorldServer builds this package and runs it as a real server so the
+// test cases travel over the wire. The server's output is written to the test
+// log and the server is stopped when the test completes.
+func TestHelloWorldServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Building the server is skipped in short mode")
+	}
+
+	c := truth.StartServer(t, &truth.Server{Package: ".", Ready: "/helloworld"})
+
+	truth.RunIntegrationTests(t, helloWorld, truth.TestCases{
+		{ExpectBody: []byte("Hello world!")},
+	}, c)
+}
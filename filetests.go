@@ -0,0 +1,454 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// FileTest is a test case declared in a YAML or JSON file so checks can be
+	// written without Go. The test is bound to a registered Definition by its
+	// Definition name or by its Method and Path:
+	//
+	//	- name: Create a user
+	//	  definition: Create User
+	//	  payload:
+	//	    name: Testy
+	//	    email: testy@example.com
+	//	  status: 201
+	//	  contains: ["Testy"]
+	//	  assertions:
+	//	    - header: Content-Type
+	//	      equals: application/json
+	//	    - json: name
+	//	      equals: Testy
+	FileTest struct {
+		Name       string            `json:"name" yaml:"name"`
+		Definition string            `json:"definition,omitempty" yaml:"definition,omitempty"`
+		Method     string            `json:"method,omitempty" yaml:"method,omitempty"`
+		Path       string            `json:"path,omitempty" yaml:"path,omitempty"`
+		Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+		Payload    interface{}       `json:"payload,omitempty" yaml:"payload,omitempty"`
+		Status     int               `json:"status,omitempty" yaml:"status,omitempty"`
+		ExpectBody interface{}       `json:"expectBody,omitempty" yaml:"expectBody,omitempty"`
+		Contains   []string          `json:"contains,omitempty" yaml:"contains,omitempty"`
+		Assertions []Assertion       `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+		Tags       []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	}
+
+	// Assertion checks a response header or a field of a JSON response body. A
+	// JSON field is addressed by a dotted path such as `user.emails.0`.
+	Assertion struct {
+		Header   string      `json:"header,omitempty" yaml:"header,omitempty"`
+		JSON     string      `json:"json,omitempty" yaml:"json,omitempty"`
+		Equals   interface{} `json:"equals,omitempty" yaml:"equals,omitempty"`
+		Contains string      `json:"contains,omitempty" yaml:"contains,omitempty"`
+		Exists   *bool       `json:"exists,omitempty" yaml:"exists,omitempty"`
+	}
+)
+
+// LoadFileTests reads every `.yaml`, `.yml` and `.json` file in the directory.
+// Each file holds a list of tests.
+func LoadFileTests(dir string) ([]FileTest, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var tests []FileTest
+	for _, n := range names {
+		ft, err := ReadFileTests(filepath.Join(dir, n))
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, ft...)
+	}
+
+	return tests, nil
+}
+
+// ReadFileTests reads the tests held by a YAML or JSON file.
+func ReadFileTests(path string) ([]FileTest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []FileTest
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(b, &tests)
+	} else {
+		err = yaml.Unmarshal(b, &tests)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode tests in %s: %s", path, err)
+	}
+
+	for i := range tests {
+		if tests[i].Name == "" {
+			tests[i].Name = fmt.Sprintf("%s #%d", filepath.Base(path), i+1)
+		}
+	}
+
+	return tests, nil
+}
+
+// WriteFileTests writes the test cases of the entries as YAML file tests which
+// refer to their Definitions by method and path.
+func WriteFileTests(w io.Writer, entries []Entry) error {
+	return writeFileTests(w, entries, false)
+}
+
+// writeFileTests writes the test cases of the entries as file tests. Tests
+// refer to a named Definition by its name when byName is set and otherwise by
+// its method and the path of the test case.
+func writeFileTests(w io.Writer, entries []Entry, byName bool) error {
+	var tests []FileTest
+
+	for _, e := range entries {
+		for _, tc := range e.Cases {
+			ft := FileTest{
+				Name:     tc.Name,
+				Path:     tc.Path,
+				Headers:  tc.Headers,
+				Status:   tc.Status,
+				Contains: tc.Contains,
+				Tags:     tc.Tags,
+			}
+
+			if byName && e.Definition.Name != "" {
+				ft.Definition = e.Definition.Name
+			} else {
+				ft.Method = e.Definition.Method
+			}
+
+			if tc.Payload != nil {
+				body, err := marshalPayload(tc.Payload)
+				if err != nil {
+					return fmt.Errorf("%s: Unable to encode payload: %s", tc.Name, err)
+				}
+				ft.Payload = bodyValue(body)
+			}
+			if tc.ExpectBody != nil {
+				ft.ExpectBody = bodyValue(tc.ExpectBody)
+			}
+
+			tests = append(tests, ft)
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(tests); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// bodyValue returns a JSON body as its decoded value so it is written as YAML,
+// and any other body as text.
+func bodyValue(b []byte) interface{} {
+	var v interface{}
+	if json.Unmarshal(b, &v) == nil {
+		return v
+	}
+	return string(b)
+}
+
+// Bind finds the Definition of the test among the entries and builds the test
+// case it declares.
+func (ft FileTest) Bind(entries []Entry) (Definition, *TestCase, error) {
+	def, ok := ft.find(entries)
+	if !ok {
+		return def, nil, fmt.Errorf("%s: No Definition matches %s", ft.Name, ft.target())
+	}
+
+	tc := &TestCase{
+		Name:       ft.Name,
+		Path:       ft.Path,
+		Headers:    ft.Headers,
+		Payload:    ft.Payload,
+		Status:     ft.Status,
+		ExpectBody: ft.expected(),
+		Contains:   ft.Contains,
+		Tags:       ft.Tags,
+	}
+
+	// Text is sent as it is written rather than as a JSON string.
+	if text, ok := ft.Payload.(string); ok {
+		tc.Payload = []byte(text)
+	}
+
+	if len(ft.Assertions) > 0 {
+		tc.Integration = func(i Integration) {
+			for _, p := range ft.check(i.RR.Header(), i.Body) {
+				i.Errorf("%s: %s", i.TC.alias, p)
+			}
+		}
+	}
+
+	return def, tc, nil
+}
+
+func (ft FileTest) find(entries []Entry) (Definition, bool) {
+	for _, e := range entries {
+		def := e.Definition
+
+		if ft.Definition != "" {
+			if def.Name == ft.Definition {
+				return def, true
+			}
+			continue
+		}
+
+		if !strings.EqualFold(def.Method, ft.Method) {
+			continue
+		}
+		if _, ok := matchPath(def.Path, ft.Path); ok || def.Path == ft.Path {
+			return def, true
+		}
+	}
+
+	return Definition{}, false
+}
+
+func (ft FileTest) target() string {
+	if ft.Definition != "" {
+		return fmt.Sprintf("the name %#v", ft.Definition)
+	}
+	return fmt.Sprintf("`%s:%s`", ft.Method, ft.Path)
+}
+
+// expected returns the body the test expects. Strings are expected verbatim and
+// any other value as JSON.
+func (ft FileTest) expected() []byte {
+	switch body := ft.ExpectBody.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(body)
+	default:
+		return JSON(body)
+	}
+}
+
+// check returns the problems with the response body and headers found by the
+// assertions.
+func (ft FileTest) check(header http.Header, body []byte) []string {
+	var problems []string
+
+	for _, a := range ft.Assertions {
+		if err := a.Check(header, body); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems
+}
+
+// Check reports if the response does not satisfy the assertion.
+func (a Assertion) Check(header http.Header, body []byte) error {
+	var (
+		actual interface{}
+		found  bool
+		name   string
+	)
+
+	switch {
+	case a.Header != "":
+		name = "Header " + a.Header
+		if v, ok := header[http.CanonicalHeaderKey(a.Header)]; ok && len(v) > 0 {
+			actual, found = v[0], true
+		}
+	case a.JSON != "":
+		name = "JSON field " + a.JSON
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("%s: Response body is not JSON", name)
+		}
+		actual, found = lookupJSON(doc, a.JSON)
+	default:
+		return fmt.Errorf("Assertion requires a header or json field")
+	}
+
+	if a.Exists != nil {
+		if found != *a.Exists {
+			return fmt.Errorf("%s: Expected exists to be %t", name, *a.Exists)
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	if !found && (a.Equals != nil || a.Contains != "") {
+		return fmt.Errorf("%s: Not found", name)
+	}
+
+	if a.Equals != nil && !jsonEqual(JSON(a.Equals), JSON(actual)) {
+		return fmt.Errorf("%s: Expected %v but received %v", name, a.Equals, actual)
+	}
+
+	if a.Contains != "" && !strings.Contains(fmt.Sprint(actual), a.Contains) {
+		return fmt.Errorf("%s: %v does not contain %#v", name, actual, a.Contains)
+	}
+
+	return nil
+}
+
+// lookupJSON follows a dotted path through decoded JSON. Array elements are
+// addressed by index.
+func lookupJSON(doc interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+// RunFileTests runs the tests declared in the YAML and JSON files of the
+// directory against the registered Definitions with the same semantics as
+// RunIntegrationTests. Register your Definitions, or run their Go tests, first.
+// Provide a client to perform full-stack tests. If nil is provided the
+// server's Mux will be called directly.
+//
+//	func TestFiles(t *testing.T) {
+//		SetupTest()
+//		truth.Register(createUserDef, nil)
+//		truth.RunFileTests(t, "testdata", nil)
+//	}
+func RunFileTests(t *testing.T, dir string, c *Client) error {
+	tests, err := LoadFileTests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := Registered()
+	caller := getCaller(2)
+
+	for _, ft := range tests {
+		def, tc, err := ft.Bind(entries)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		cases := TestCases{tc}
+		cases.init(def, caller)
+
+		t.Run(tc.Name, func(t *testing.T) {
+			if err = NewRunner(c)(t, def, *tc); err != nil {
+				t.Error(err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FileReport lists the file tests which failed outside of go test.
+type FileReport struct {
+	Passed   int
+	Failures []FileFailure
+}
+
+// FileFailure describes how a file test failed.
+type FileFailure struct {
+	Test     FileTest
+	Problems []string
+}
+
+// VerifyFileTests runs the tests against the Definitions of the entries
+// without go test, which is how the truth command runs them against a remote
+// host. Provide a client to test a running server. If nil is provided the
+// server's Mux will be called directly.
+func VerifyFileTests(tests []FileTest, entries []Entry, c *Client) (*FileReport, error) {
+	report := &FileReport{}
+
+	for _, ft := range tests {
+		def, tc, err := ft.Bind(entries)
+		if err != nil {
+			report.Failures = append(report.Failures, FileFailure{Test: ft, Problems: []string{err.Error()}})
+			continue
+		}
+
+		cases := TestCases{tc}
+		cases.init(def, "the truth command")
+
+		if err := preflight(def, tc.Path); err != nil {
+			return report, fmt.Errorf("%s: Preflight failed: %s", tc.alias, err.Error())
+		}
+
+		RR, body, err := exchange(c, def, *tc)
+		if err != nil {
+			return report, err
+		}
+
+		problems := checkResponse(statusOf(tc), tc.ExpectBody, tc.Contains, RR.Code, body)
+		problems = append(problems, ft.check(RR.Header(), body)...)
+
+		if len(problems) > 0 {
+			report.Failures = append(report.Failures, FileFailure{Test: ft, Problems: problems})
+			continue
+		}
+
+		report.Passed++
+	}
+
+	return report, nil
+}
+
+// Failed reports if any test failed.
+func (r *FileReport) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// String summarizes the report with a line per failed test.
+func (r *FileReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d tests passed, %d failed\n", r.Passed, len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "%s\n", f.Test.Name)
+		for _, p := range f.Problems {
+			fmt.Fprintf(&b, "\t%s\n", p)
+		}
+	}
+
+	return b.String()
+}
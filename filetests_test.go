@@ -0,0 +1,206 @@
+package truth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLookupJSON(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"user":{"name":"Ann","emails":["ann@example.com"],"age":30}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{path: "user.name", expected: "Ann", found: true},
+		{path: "user.emails.0", expected: "ann@example.com", found: true},
+		{path: "user.age", expected: 30.0, found: true},
+		{path: "user.emails.1"},
+		{path: "user.emails.-1"},
+		{path: "user.emails.first"},
+		{path: "user.emails.0x0"},
+		{path: "user.phone"},
+		{path: "user.name.first"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			actual, found := lookupJSON(doc, test.path)
+			if found != test.found || actual != test.expected {
+				t.Errorf("Expected %v (%t) but received %v (%t)", test.expected, test.found, actual, found)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	yes, no := true, false
+	header := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	body := []byte(`{"id":1,"name":"Ann","tags":["admin"]}`)
+
+	tests := []struct {
+		name      string
+		assertion Assertion
+		body      []byte
+		problem   string
+	}{
+		{name: "header equals", assertion: Assertion{Header: "content-type", Equals: "application/json; charset=utf-8"}},
+		{name: "header contains", assertion: Assertion{Header: "Content-Type", Contains: "json"}},
+		{name: "header differs", assertion: Assertion{Header: "Content-Type", Equals: "text/plain"}, problem: "Header Content-Type: Expected text/plain but received application/json; charset=utf-8"},
+		{name: "header missing", assertion: Assertion{Header: "ETag", Equals: "1"}, problem: "Header ETag: Not found"},
+		{name: "header absent", assertion: Assertion{Header: "ETag", Exists: &no}},
+		{name: "number equals", assertion: Assertion{JSON: "id", Equals: 1}},
+		{name: "array equals", assertion: Assertion{JSON: "tags", Equals: []string{"admin"}}},
+		{name: "field differs", assertion: Assertion{JSON: "name", Equals: "Bob"}, problem: "JSON field name: Expected Bob but received Ann"},
+		{name: "field contains", assertion: Assertion{JSON: "tags.0", Contains: "adm"}},
+		{name: "field does not contain", assertion: Assertion{JSON: "name", Contains: "Bob"}, problem: `JSON field name: Ann does not contain "Bob"`},
+		{name: "field exists", assertion: Assertion{JSON: "name", Exists: &yes}},
+		{name: "field does not exist", assertion: Assertion{JSON: "email", Exists: &yes}, problem: "JSON field email: Expected exists to be true"},
+		{name: "field exists unexpectedly", assertion: Assertion{JSON: "id", Exists: &no}, problem: "JSON field id: Expected exists to be false"},
+		{name: "not JSON", assertion: Assertion{JSON: "id", Equals: 1}, body: []byte("<html>"), problem: "JSON field id: Response body is not JSON"},
+		{name: "nothing to check", assertion: Assertion{Equals: 1}, problem: "Assertion requires a header or json field"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := body
+			if test.body != nil {
+				b = test.body
+			}
+
+			err := test.assertion.Check(header, b)
+			if test.problem == "" && err != nil {
+				t.Errorf("Expected no problem but received %s", err)
+			}
+			if test.problem != "" && (err == nil || err.Error() != test.problem) {
+				t.Errorf("Expected %s but received %v", test.problem, err)
+			}
+		})
+	}
+}
+
+func TestFileTestBind(t *testing.T) {
+	entries := []Entry{
+		{Definition: Definition{Method: "GET", Path: "/users/{id}", Name: "Get User"}},
+		{Definition: Definition{Method: "POST", Path: "/users", Name: "Create User"}},
+	}
+
+	tests := []struct {
+		name       string
+		ft         FileTest
+		definition string
+		payload    string
+		expectBody string
+		err        string
+	}{
+		{name: "by name", ft: FileTest{Name: "create", Definition: "Create User", Payload: map[string]interface{}{"name": "Ann"}}, definition: "Create User", payload: `{"name":"Ann"}`},
+		{name: "by method and path", ft: FileTest{Name: "get", Method: "get", Path: "/users/1", ExpectBody: map[string]interface{}{"id": 1}}, definition: "Get User", expectBody: `{"id":1}`},
+		{name: "by route", ft: FileTest{Name: "route", Method: "GET", Path: "/users/{id}"}, definition: "Get User"},
+		{name: "text", ft: FileTest{Name: "text", Definition: "Create User", Payload: "name=Ann", ExpectBody: "created"}, definition: "Create User", payload: "name=Ann", expectBody: "created"},
+		{name: "unknown name", ft: FileTest{Name: "unknown", Definition: "Delete User"}, err: `unknown: No Definition matches the name "Delete User"`},
+		{name: "unknown route", ft: FileTest{Name: "unknown", Method: "DELETE", Path: "/users/1"}, err: "unknown: No Definition matches `DELETE:/users/1`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def, tc, err := test.ft.Bind(entries)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Expected %s but received %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if def.Name != test.definition {
+				t.Errorf("Expected the Definition %s but received %s", test.definition, def.Name)
+			}
+			if tc.Name != test.ft.Name || tc.Path != test.ft.Path {
+				t.Errorf("Expected the name and path of the file test but received %s and %s", tc.Name, tc.Path)
+			}
+
+			payload := ""
+			switch p := tc.Payload.(type) {
+			case []byte:
+				payload = string(p)
+			case nil:
+			default:
+				payload = string(JSON(p))
+			}
+			if payload != test.payload {
+				t.Errorf("Expected payload %s but received %s", test.payload, payload)
+			}
+			if string(tc.ExpectBody) != test.expectBody {
+				t.Errorf("Expected body %s but received %s", test.expectBody, tc.ExpectBody)
+			}
+		})
+	}
+}
+
+func TestVerifyFileTests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", MIMETypeJSON)
+		switch r.URL.Path {
+		case "/users/1":
+			rw.Write([]byte(`{"name": "Ann", "id": 1}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"error":"Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	entries := []Entry{{Definition: Definition{
+		Method:           "GET",
+		Path:             "/users/{id}",
+		Name:             "Get User",
+		MIMETypeRequest:  MIMETypeJSON,
+		MIMETypeResponse: MIMETypeJSON,
+	}}}
+
+	tests := []FileTest{
+		{
+			Name:       "found",
+			Definition: "Get User",
+			Path:       "/users/1",
+			ExpectBody: map[string]interface{}{"id": 1, "name": "Ann"},
+			Assertions: []Assertion{{Header: "Content-Type", Contains: "json"}},
+		},
+		{Name: "missing", Definition: "Get User", Path: "/users/2", Status: 404, Contains: []string{"Not Found"}},
+		{Name: "wrong status", Definition: "Get User", Path: "/users/3"},
+		{Name: "failed assertion", Definition: "Get User", Path: "/users/1", Assertions: []Assertion{{JSON: "name", Equals: "Bob"}}},
+		{Name: "unbound", Definition: "Delete User"},
+	}
+
+	report, err := VerifyFileTests(tests, entries, NewClient(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Failed() || report.Passed != 2 {
+		t.Errorf("Expected 2 tests to pass but received %s", report)
+	}
+
+	var failed []string
+	for _, f := range report.Failures {
+		failed = append(failed, f.Test.Name+": "+strings.Join(f.Problems, ", "))
+	}
+
+	expected := []string{
+		"wrong status: Expected statuscode 200 but received 404",
+		"failed assertion: JSON field name: Expected Bob but received Ann",
+		`unbound: unbound: No Definition matches the name "Delete User"`,
+	}
+	if actual := strings.Join(failed, "\n"); actual != strings.Join(expected, "\n") {
+		t.Errorf("Expected failures\n%s\nbut received\n%s", strings.Join(expected, "\n"), actual)
+	}
+}
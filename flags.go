@@ -0,0 +1,153 @@
+package truth
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The flags retarget the tests of a package without editing them so the same
+// `go test ./...` is a post-deploy smoke test:
+//
+//	go test ./... -truth.env=staging -truth.tags=smoke
+//
+// Each flag defaults to an environment variable: TRUTH_HOST, TRUTH_MODE,
+// TRUTH_ENV, TRUTH_TAGS, TRUTH_UPDATE, TRUTH_SNIPPETS and TRUTH_VERBOSE.
+var (
+	hostFlag     = os.Getenv("TRUTH_HOST")
+	mode         = os.Getenv("TRUTH_MODE")
+	envFlag      = os.Getenv("TRUTH_ENV")
+	tagsFlag     = os.Getenv("TRUTH_TAGS")
+	updateFlag   = os.Getenv("TRUTH_UPDATE") != ""
+	snippetsFlag = os.Getenv("TRUTH_SNIPPETS")
+)
+
+func init() {
+	verbose, _ = strconv.ParseBool(os.Getenv("TRUTH_VERBOSE"))
+
+	// Only test binaries get the flags on their command line so programs which
+	// import the package, such as a server sharing its Definitions, do not.
+	if testing.Testing() {
+		RegisterFlags(flag.CommandLine)
+	}
+}
+
+// RegisterFlags adds the `-truth.*` flags to the flag set. Test binaries have
+// them on the command line already. Call it to retarget tests run by other
+// programs:
+//
+//	truth.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&hostFlag, "truth.host", hostFlag, "Base URL of a remote server tests without a Client run against")
+	fs.StringVar(&mode, "truth.mode", mode, "How tests without a Client reach the server: inprocess, server, tls, remote or parity")
+	fs.StringVar(&envFlag, "truth.env", envFlag, "Name of a registered Environment tests without a Client run against")
+	fs.StringVar(&tagsFlag, "truth.tags", tagsFlag, "Comma separated tags selecting test cases. Prefix a tag with ! to exclude it")
+	fs.BoolVar(&updateFlag, "truth.update", updateFlag, "Write the API snapshot baseline checked by CheckCompatibility")
+	fs.StringVar(&snippetsFlag, "truth.snippets", snippetsFlag, "Comma separated commands failed test cases render to reproduce their request: curl, httpie or none")
+	fs.BoolVar(&verbose, "truth.verbose", verbose, "Print every test case and request as it runs")
+}
+
+type (
+	// Environment describes a deployment of the server, such as staging, that
+	// tests run against when it is selected by the `-truth.env` flag.
+	Environment struct {
+		Host string
+		// BasePath prefixes the path of every request such as `/api/v2`.
+		BasePath string
+
+		// Credentials and Identities are added to those of the package. See
+		// SetCredentials and SetIdentity.
+		Credentials map[string]Credentials
+		Identities  map[string]Credentials
+	}
+)
+
+var (
+	environmentsMu sync.Mutex
+	environments   = map[string]Environment{}
+)
+
+// RegisterEnvironment makes the environment available to the `-truth.env` flag.
+// Credentials are typically read from the environment of the test:
+//
+//	truth.RegisterEnvironment("staging", truth.Environment{
+//		Host:     "https://staging.example.com",
+//		BasePath: "/api",
+//		Credentials: map[string]truth.Credentials{
+//			truth.AuthorizationCredentials: truth.Bearer(os.Getenv("STAGING_TOKEN")),
+//		},
+//	})
+func RegisterEnvironment(name string, env Environment) {
+	environmentsMu.Lock()
+	defer environmentsMu.Unlock()
+
+	environments[name] = env
+}
+
+// SetHost points tests without a Client at a remote server, overriding the
+// `-truth.host` flag.
+func SetHost(host string) {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	hostFlag = host
+}
+
+// environment returns the Environment selected by the flags. The `-truth.host`
+// flag overrides the Host of the named environment.
+func environment() (Environment, error) {
+	var env Environment
+
+	if envFlag != "" {
+		environmentsMu.Lock()
+		e, ok := environments[envFlag]
+		environmentsMu.Unlock()
+
+		if !ok {
+			return env, fmt.Errorf("Unknown environment %#v", envFlag)
+		}
+		env = e
+	}
+
+	if hostFlag != "" {
+		env.Host = hostFlag
+	}
+
+	return env, nil
+}
+
+// selected reports if the test case has the tags selected by `-truth.tags`. A
+// test case is selected when it has any of the tags and none of the excluded
+// tags. Without tags every test case is selected.
+func selected(tc TestCase) bool {
+	if tagsFlag == "" {
+		return true
+	}
+
+	has := map[string]bool{}
+	for _, t := range tc.Tags {
+		has[t] = true
+	}
+
+	included, requireInclusion := false, false
+	for _, t := range strings.Split(tagsFlag, ",") {
+		t = strings.TrimSpace(t)
+		switch {
+		case t == "":
+		case strings.HasPrefix(t, "!"):
+			if has[t[1:]] {
+				return false
+			}
+		default:
+			requireInclusion = true
+			included = included || has[t]
+		}
+	}
+
+	return included || !requireInclusion
+}
@@ -0,0 +1,51 @@
+package truth
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestSelected(t *testing.T) {
+	defer func(tags string) { tagsFlag = tags }(tagsFlag)
+
+	tests := []struct {
+		name     string
+		flag     string
+		tags     []string
+		expected bool
+	}{
+		{name: "no flag", tags: []string{"slow"}, expected: true},
+		{name: "included", flag: "smoke", tags: []string{"smoke"}, expected: true},
+		{name: "not included", flag: "smoke", tags: []string{"slow"}},
+		{name: "untagged not included", flag: "smoke"},
+		{name: "excluded", flag: "!slow", tags: []string{"slow"}},
+		{name: "untagged not excluded", flag: "!slow", expected: true},
+		{name: "included and excluded", flag: "smoke, !slow", tags: []string{"smoke", "slow"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tagsFlag = test.flag
+			if actual := selected(TestCase{Tags: test.tags}); actual != test.expected {
+				t.Errorf("Expected %t but received %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	defer func(tags string, update bool) { tagsFlag, updateFlag = tags, update }(tagsFlag, updateFlag)
+
+	if flag.Lookup("truth.tags") == nil {
+		t.Error("Expected the flags to be registered on the command line of a test binary")
+	}
+
+	fs := flag.NewFlagSet("program", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"-truth.tags=smoke", "-truth.update"}); err != nil {
+		t.Fatal(err)
+	}
+	if tagsFlag != "smoke" || !updateFlag {
+		t.Errorf("Expected the flags to be set but received tags %q and update %t", tagsFlag, updateFlag)
+	}
+}
@@ -0,0 +1,126 @@
+package truth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Fuzz registers a native Go fuzz target for the endpoint described by the
+// Definition. The corpus is seeded from the path, query string and payload of
+// every test case. The fuzzer mutates the route variables, each independently of
+// the others, the query string and the request body and sends each input
+// in-process to the mux provided with SetMux.
+//
+// An input fails when the handler panics, responds with a 5XX status code or
+// responds successfully with a body which violates the ResponseBody:
+//
+//	func FuzzCreateUser(f *testing.F) {
+//		SetupTest()
+//		truth.Fuzz(f, createUserDef, tests)
+//	}
+//
+// Run the target with `go test -fuzz FuzzCreateUser`.
+func Fuzz(f *testing.F, def Definition, cases TestCases) {
+	cases.init(def, getCaller(2))
+
+	if err := preflight(def, def.Path); err != nil {
+		f.Fatalf("Preflight failed for `%s:%s`: %s", def.Method, def.Path, err)
+	}
+
+	if muxUnderTest == nil {
+		f.Fatal(errNoMux)
+	}
+
+	for _, tc := range cases {
+		params, query := fuzzSeed(def, tc)
+
+		var body []byte
+		if tc.Payload != nil {
+			var err error
+			if body, err = marshalPayload(tc.Payload); err != nil {
+				f.Fatalf("%s: Unable to encode payload: %s", tc.alias, err)
+			}
+		}
+
+		f.Add(params, query, body)
+	}
+
+	f.Fuzz(func(t *testing.T, params, query string, body []byte) {
+		tc := TestCase{
+			Path: fuzzPath(def.Path, params),
+		}
+		if len(body) > 0 {
+			tc.Payload = body
+		}
+		tc.init(def, 0, 0, "fuzz")
+
+		req, err := integrationClient.BuildRequest(def, tc)
+		if err != nil {
+			t.Skip(err)
+		}
+		// Assigned directly so the handler sees the query exactly as mutated.
+		req.URL.RawQuery = query
+
+		RR := httptest.NewRecorder()
+		if p := serveRecover(muxUnderTest, RR, req); p != nil {
+			t.Fatalf("Handler panicked at `%s:%s` with body %q: %v", req.Method, req.URL.RequestURI(), body, p)
+		}
+
+		if RR.Code >= 500 {
+			t.Fatalf("Received statuscode %d at `%s:%s` with body %q", RR.Code, req.Method, req.URL.RequestURI(), body)
+		}
+
+		if RR.Code >= 200 && RR.Code < 300 && RR.Body.Len() > 0 {
+			if err := def.ResponseBody.Validate(RR.Body.Bytes()); err != nil {
+				t.Fatalf("Response at `%s:%s` with body %q violates the ResponseBody: %s", req.Method, req.URL.RequestURI(), body, err)
+			}
+		}
+	})
+}
+
+// fuzzSeed extracts the route variables, separated by fuzzSeparator, and the
+// query string of the test case's path to seed the fuzz corpus.
+func fuzzSeed(def Definition, tc *TestCase) (params, query string) {
+	path, query := splitPath(tc.Path)
+
+	names := pathParams(def.Path)
+	values := make([]string, len(names))
+	if matched, ok := matchPath(def.Path, path); ok {
+		for i, name := range names {
+			values[i] = matched[name]
+		}
+	}
+
+	return strings.Join(values, fuzzSeparator), query
+}
+
+// fuzzSeparator separates the values of the route variables within a fuzz input.
+const fuzzSeparator = "\n"
+
+// fuzzPath fills the route variables of the path template in order with the
+// values of the fuzz input. A variable without a value is left empty.
+func fuzzPath(template, params string) string {
+	names := pathParams(template)
+	values := map[string]string{}
+	if len(names) > 0 {
+		for i, v := range strings.SplitN(params, fuzzSeparator, len(names)) {
+			values[names[i]] = v
+		}
+	}
+
+	return fillPath(template, func(name string) string { return values[name] })
+}
+
+// serveRecover calls the handler and returns the value of any panic raised
+// while serving the request.
+func serveRecover(h http.Handler, rw http.ResponseWriter, req *http.Request) (p interface{}) {
+	defer func() {
+		p = recover()
+	}()
+
+	h.ServeHTTP(rw, req)
+
+	return nil
+}
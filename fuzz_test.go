@@ -0,0 +1,92 @@
+package truth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFuzzSeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		params   string
+		query    string
+	}{
+		{name: "no variables", template: "/users", path: "/users?page=2", query: "page=2"},
+		{name: "one variable", template: "/users/{id}", path: "/users/7", params: "7"},
+		{name: "several variables", template: "/orgs/{org}/users/{id}", path: "/orgs/acme/users/7?full", params: "acme\n7", query: "full"},
+		{name: "unmatched path", template: "/orgs/{org}/users/{id}", path: "/other", params: "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, query := fuzzSeed(Definition{Path: test.template}, &TestCase{Path: test.path})
+			if params != test.params || query != test.query {
+				t.Errorf("Expected %q and %q but received %q and %q", test.params, test.query, params, query)
+			}
+		})
+	}
+}
+
+func TestFuzzPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   string
+		expected string
+	}{
+		{name: "no variables", template: "/users", params: "ignored", expected: "/users"},
+		{name: "independent values", template: "/orgs/{org}/users/{id}", params: "acme\n7", expected: "/orgs/acme/users/7"},
+		{name: "missing value", template: "/orgs/{org}/users/{id}", params: "acme", expected: "/orgs/acme/users/"},
+		{name: "separator in last value", template: "/orgs/{org}/users/{id}", params: "acme\n7\n8", expected: "/orgs/acme/users/7%0A8"},
+		{name: "escaped", template: "/files/{name}", params: "a/b c", expected: "/files/a%2Fb%20c"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := fuzzPath(test.template, test.params); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+// FuzzSeveralVariables runs the seed corpus of a route with several variables
+// and a JSON payload. The handler fails when the variables hold the same value
+// or the payload is not the one seeded.
+func FuzzSeveralVariables(f *testing.F) {
+	mux := muxUnderTest
+	f.Cleanup(func() { muxUnderTest = mux })
+
+	SetMux(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		segments := strings.Split(req.URL.Path, "/")
+		if len(segments) != 5 || segments[2] == segments[4] {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var user struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&user); err != nil || user.Name != "Ann" {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+
+	def := Definition{
+		Method:           PUT,
+		Path:             "/orgs/{org}/users/{id}",
+		MIMETypeRequest:  MIMETypeJSON,
+		MIMETypeResponse: MIMETypeJSON,
+	}
+
+	Fuzz(f, def, TestCases{
+		{Name: "update", Path: "/orgs/acme/users/7", Payload: map[string]string{"name": "Ann"}},
+	})
+}
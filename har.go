@@ -0,0 +1,350 @@
+package truth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HARVersion is the version of the HAR format read and written.
+const HARVersion = "1.2"
+
+type (
+	harFile struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime time.Time   `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+var (
+	exchangesMu       sync.Mutex
+	recordExchanges   bool
+	recordedExchanges []Exchange
+)
+
+// ToggleRecordExchanges starts or stops recording the exchanges made by test
+// cases so they can be shared as a HAR file. Secrets are redacted as exchanges
+// are recorded.
+//
+//	func TestMain(m *testing.M) {
+//		truth.ToggleRecordExchanges()
+//		code := m.Run()
+//		truth.ExportHAR("testdata/traffic.har")
+//		os.Exit(code)
+//	}
+func ToggleRecordExchanges() {
+	exchangesMu.Lock()
+	defer exchangesMu.Unlock()
+
+	recordExchanges = !recordExchanges
+}
+
+// RecordedExchanges returns the exchanges recorded since ToggleRecordExchanges
+// started recording.
+func RecordedExchanges() []Exchange {
+	exchangesMu.Lock()
+	defer exchangesMu.Unlock()
+
+	return append([]Exchange(nil), recordedExchanges...)
+}
+
+// recordExchange records the request made by the Client and its response when
+// recording is on. Secrets are redacted as told by redactNames.
+func recordExchange(c *Client, req *http.Request, started time.Time, status int, header http.Header, body []byte) {
+	exchangesMu.Lock()
+	on := recordExchanges
+	exchangesMu.Unlock()
+
+	if !on {
+		return
+	}
+
+	x := Exchange{
+		Method:          req.Method,
+		URL:             req.URL.RequestURI(),
+		RequestHeaders:  req.Header.Clone(),
+		Status:          status,
+		ResponseHeaders: header.Clone(),
+		ResponseBody:    body,
+		Started:         started,
+		Duration:        time.Since(started),
+	}
+	if req.URL.Host != "" {
+		x.Host = req.URL.Scheme + "://" + req.URL.Host
+	}
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			x.RequestBody, _ = ioutil.ReadAll(rc)
+			rc.Close()
+		}
+	}
+
+	exchangesMu.Lock()
+	defer exchangesMu.Unlock()
+
+	headers, fields := redactNames(c)
+	recordedExchanges = append(recordedExchanges, redact(x, headers, fields))
+}
+
+// ExportHAR writes the recorded exchanges to a HAR file at the path.
+func ExportHAR(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteHAR(f, RecordedExchanges()); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteHAR writes the exchanges as a HAR document. Exchanges made in-process
+// are written with http://localhost as their host. The comment of each entry is
+// a curl command reproducing the request. Secrets are redacted from the URL,
+// query string, headers, bodies and comment of every entry, including the API
+// keys registered with the Client of the package.
+func WriteHAR(w io.Writer, exchanges []Exchange) error {
+	headers, fields := redactNames(nil)
+
+	doc := harFile{Log: harLog{
+		Version: HARVersion,
+		Creator: harCreator{Name: "truth", Version: "1"},
+		Entries: make([]harEntry, 0, len(exchanges)),
+	}}
+
+	for _, x := range exchanges {
+		x = redact(x, headers, fields)
+
+		host := x.Host
+		if host == "" {
+			host = "http://localhost"
+		}
+
+		ms := float64(x.Duration) / float64(time.Millisecond)
+
+		e := harEntry{
+			StartedDateTime: x.Started,
+			Time:            ms,
+			Request: harRequest{
+				Method:      x.Method,
+				URL:         host + x.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(x.RequestHeaders),
+				QueryString: []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    len(x.RequestBody),
+			},
+			Response: harResponse{
+				Status:      x.Status,
+				StatusText:  http.StatusText(x.Status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(x.ResponseHeaders),
+				Content:     harContent{Size: len(x.ResponseBody), MimeType: x.ResponseHeaders.Get("Content-Type")},
+				HeadersSize: -1,
+				BodySize:    len(x.ResponseBody),
+			},
+			Timings: harTimings{Wait: ms},
+			Comment: x.Curl(),
+		}
+
+		if u, err := url.Parse(x.URL); err == nil {
+			e.Request.QueryString = harHeaders(http.Header(u.Query()))
+		}
+
+		if len(x.RequestBody) > 0 {
+			e.Request.PostData = &harPostData{MimeType: x.RequestHeaders.Get("Content-Type"), Text: string(x.RequestBody)}
+		}
+
+		if utf8.Valid(x.ResponseBody) {
+			e.Response.Content.Text = string(x.ResponseBody)
+		} else {
+			e.Response.Content.Text = base64.StdEncoding.EncodeToString(x.ResponseBody)
+			e.Response.Content.Encoding = "base64"
+		}
+
+		doc.Log.Entries = append(doc.Log.Entries, e)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// harHeaders lists the values of the headers sorted by name.
+func harHeaders(h http.Header) []harNameValue {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range h[k] {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// LoadHAR reads the exchanges of the HAR file at the path. See ReadHAR.
+func LoadHAR(path string) ([]Exchange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	exchanges, err := ReadHAR(f)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read HAR file %s: %s", path, err)
+	}
+	return exchanges, nil
+}
+
+// ReadHAR reads the exchanges of a HAR document, such as one saved from the
+// network panel of a browser, with their secrets redacted, including the API
+// keys registered with the Client of the package. Use a Recording to turn the
+// exchanges into test cases.
+func ReadHAR(r io.Reader) ([]Exchange, error) {
+	headers, fields := redactNames(nil)
+
+	var doc harFile
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	exchanges := make([]Exchange, 0, len(doc.Log.Entries))
+
+	for i, e := range doc.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Entry %d has an invalid URL: %s", i, err)
+		}
+
+		x := Exchange{
+			Method:          strings.ToUpper(e.Request.Method),
+			URL:             u.RequestURI(),
+			RequestHeaders:  http.Header{},
+			Status:          e.Response.Status,
+			ResponseHeaders: http.Header{},
+			Started:         e.StartedDateTime,
+			Duration:        time.Duration(e.Time * float64(time.Millisecond)),
+		}
+		if u.Host != "" {
+			x.Host = u.Scheme + "://" + u.Host
+		}
+
+		for _, h := range e.Request.Headers {
+			// HTTP/2 pseudo-headers and the host are part of the URL.
+			if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "Host") {
+				continue
+			}
+			x.RequestHeaders.Add(h.Name, h.Value)
+		}
+		for _, h := range e.Response.Headers {
+			if strings.HasPrefix(h.Name, ":") {
+				continue
+			}
+			x.ResponseHeaders.Add(h.Name, h.Value)
+		}
+
+		if e.Request.PostData != nil && e.Request.PostData.Text != "" {
+			x.RequestBody = []byte(e.Request.PostData.Text)
+		}
+
+		if e.Response.Content.Encoding == "base64" {
+			if x.ResponseBody, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+				return nil, fmt.Errorf("Entry %d has an invalid base64 response body: %s", i, err)
+			}
+		} else if e.Response.Content.Text != "" {
+			x.ResponseBody = []byte(e.Response.Content.Text)
+		}
+
+		exchanges = append(exchanges, redact(x, headers, fields))
+	}
+
+	return exchanges, nil
+}
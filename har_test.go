@@ -0,0 +1,242 @@
+package truth
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHARRoundTrip(t *testing.T) {
+	started := time.Unix(1700000000, 0).UTC()
+
+	tests := []struct {
+		name     string
+		exchange Exchange
+	}{
+		{
+			name: "json",
+			exchange: Exchange{
+				Method:          "POST",
+				Host:            "https://api.example.com",
+				URL:             "/users?team=a&team=b",
+				RequestHeaders:  http.Header{"Content-Type": {MIMETypeJSON}},
+				RequestBody:     []byte(`{"name":"Ann"}`),
+				Status:          201,
+				ResponseHeaders: http.Header{"Content-Type": {MIMETypeJSON}},
+				ResponseBody:    []byte(`{"id":1}`),
+				Started:         started,
+				Duration:        25 * time.Millisecond,
+			},
+		},
+		{
+			name: "binary",
+			exchange: Exchange{
+				Method:          "GET",
+				Host:            "http://localhost",
+				URL:             "/avatar.png",
+				RequestHeaders:  http.Header{},
+				Status:          200,
+				ResponseHeaders: http.Header{"Content-Type": {"image/png"}},
+				ResponseBody:    []byte{0x89, 'P', 'N', 'G', 0xff, 0x00},
+				Started:         started,
+			},
+		},
+		{
+			name: "empty",
+			exchange: Exchange{
+				Method:          "DELETE",
+				Host:            "http://localhost",
+				URL:             "/users/1",
+				RequestHeaders:  http.Header{},
+				Status:          204,
+				ResponseHeaders: http.Header{},
+				Started:         started,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteHAR(&b, []Exchange{test.exchange}); err != nil {
+				t.Fatal(err)
+			}
+
+			exchanges, err := ReadHAR(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(exchanges) != 1 {
+				t.Fatalf("Expected 1 exchange but received %d", len(exchanges))
+			}
+			if actual, expected := JSON(exchanges[0]), JSON(test.exchange); string(actual) != string(expected) {
+				t.Errorf("Expected %s but received %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteHARLocalhost(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteHAR(&b, []Exchange{{Method: "GET", URL: "/users", Status: 200}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"url": "http://localhost/users"`) {
+		t.Errorf("Expected exchanges made in-process to be written with http://localhost but received:\n%s", b.String())
+	}
+}
+
+func TestReadHAR(t *testing.T) {
+	tests := []struct {
+		name     string
+		har      string
+		expected string
+		secrets  []string
+		invalid  bool
+	}{
+		{
+			name: "credentials",
+			har: `{"log":{"entries":[{"request":{"method":"post","url":"https://api.example.com/sessions",
+				"headers":[{"name":":authority","value":"api.example.com"},{"name":"Host","value":"api.example.com"},{"name":"Cookie","value":"session=abc"},{"name":"Authorization","value":"Bearer secret"}],
+				"postData":{"mimeType":"application/json","text":"{\"password\":\"hunter2\"}"}},
+				"response":{"status":201,"headers":[{"name":"Set-Cookie","value":"session=def"}],"content":{"text":"{\"access_token\":\"xyz\"}"}}}]}}`,
+			expected: `POST https://api.example.com /sessions 201`,
+			secrets:  []string{":authority", "Host", "session=abc", "Bearer secret", "hunter2", "session=def", "xyz"},
+		},
+		{
+			name:    "invalid base64",
+			har:     `{"log":{"entries":[{"request":{"method":"GET","url":"http://localhost/"},"response":{"status":200,"content":{"text":"!","encoding":"base64"}}}]}}`,
+			invalid: true,
+		},
+		{
+			name:    "invalid url",
+			har:     `{"log":{"entries":[{"request":{"method":"GET","url":"http://local host/"},"response":{"status":200}}]}}`,
+			invalid: true,
+		},
+		{
+			name:    "invalid json",
+			har:     `{"log":`,
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exchanges, err := ReadHAR(strings.NewReader(test.har))
+			if test.invalid {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			x := exchanges[0]
+			if actual := x.Method + " " + x.Host + " " + x.URL + " " + strconv.Itoa(x.Status); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+			recorded := string(JSON(x.RequestHeaders)) + string(JSON(x.ResponseHeaders)) + string(x.RequestBody) + string(x.ResponseBody)
+			for _, secret := range test.secrets {
+				if strings.Contains(recorded, secret) {
+					t.Errorf("Expected %s to be removed or redacted but received %s", secret, recorded)
+				}
+			}
+		})
+	}
+}
+
+// withClient replaces the Client of the package for the test.
+func withClient(t *testing.T, c *Client) {
+	client := integrationClient
+	integrationClient = c
+	t.Cleanup(func() { integrationClient = client })
+}
+
+func TestHARRedactsAPIKeys(t *testing.T) {
+	withClient(t, NewClient(""))
+	SetCredentials(AuthorizationCredentials, APIKey{Name: "X-Service-Token", Value: "s3cret"})
+	SetIdentity("partner", APIKey{Name: "sig", Value: "s3cret", InQuery: true})
+
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}, "X-Service-Token": {"s3cret"}}
+	x := Exchange{
+		Method:          "POST",
+		URL:             "/sessions?sig=s3cret&q=ann",
+		RequestHeaders:  form,
+		RequestBody:     []byte("username=ann&password=s3cret"),
+		Status:          200,
+		ResponseHeaders: http.Header{},
+	}
+
+	read := func(t *testing.T) string {
+		var b bytes.Buffer
+		if err := WriteHAR(&b, []Exchange{x}); err != nil {
+			t.Fatal(err)
+		}
+		har := strings.Replace(b.String(), Redacted, "s3cret", -1)
+
+		exchanges, err := ReadHAR(strings.NewReader(har))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(JSON(exchanges[0].RequestHeaders)) + exchanges[0].URL + string(exchanges[0].RequestBody)
+	}
+
+	record := func(t *testing.T) string {
+		defer func(on bool, recorded []Exchange) {
+			recordExchanges, recordedExchanges = on, recorded
+		}(recordExchanges, recordedExchanges)
+		recordExchanges, recordedExchanges = true, nil
+
+		req, err := http.NewRequest(x.Method, "http://localhost"+x.URL, bytes.NewReader(x.RequestBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = form.Clone()
+		recordExchange(nil, req, time.Now(), 200, http.Header{}, nil)
+
+		r := RecordedExchanges()[0]
+		return string(JSON(r.RequestHeaders)) + r.URL + string(r.RequestBody)
+	}
+
+	tests := []struct {
+		name   string
+		output func(t *testing.T) string
+	}{
+		{
+			name: "write",
+			output: func(t *testing.T) string {
+				var b bytes.Buffer
+				if err := WriteHAR(&b, []Exchange{x}); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(b.String(), `"comment": "curl`) {
+					t.Errorf("Expected the entry to be commented with curl but received:\n%s", b.String())
+				}
+				return b.String()
+			},
+		},
+		{name: "read", output: read},
+		{name: "record", output: record},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := test.output(t)
+			if strings.Contains(out, "s3cret") {
+				t.Errorf("Expected the API keys and fields to be redacted but received:\n%s", out)
+			}
+			if !strings.Contains(out, "q=ann") || !strings.Contains(out, "username=ann") {
+				t.Errorf("Expected the other fields to be kept but received:\n%s", out)
+			}
+		})
+	}
+
+	if x.RequestHeaders.Get("X-Service-Token") != "s3cret" || x.URL != "/sessions?sig=s3cret&q=ann" {
+		t.Error("Expected the exchange to be left unchanged")
+	}
+}
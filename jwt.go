@@ -0,0 +1,285 @@
+package truth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// JWTAlgRS256 signs tokens with RSASSA-PKCS1-v1_5 using SHA-256.
+	JWTAlgRS256 = "RS256"
+	// JWTAlgES256 signs tokens with ECDSA using P-256 and SHA-256.
+	JWTAlgES256 = "ES256"
+	// JWTAlgHS256 signs tokens with HMAC using SHA-256.
+	JWTAlgHS256 = "HS256"
+)
+
+type (
+	// Claims holds the claims of a JSON Web Token.
+	Claims map[string]interface{}
+
+	// TokenFactory generates a signing key and mints JSON Web Tokens for test
+	// identities. Point the application under test at the JWKS handler, or at
+	// the Secret for HS256, so it validates the tokens minted by the factory.
+	TokenFactory struct {
+		// Alg is the signing algorithm. One of JWTAlgRS256, JWTAlgES256 or JWTAlgHS256.
+		Alg string
+		// KeyID is the `kid` header of minted tokens and the id of the published key.
+		KeyID string
+		// Issuer is the `iss` claim of tokens which do not provide one.
+		Issuer string
+		// Audience is the `aud` claim of tokens which do not provide one.
+		Audience string
+		// TTL is the lifetime of tokens which do not provide an `exp` claim.
+		TTL time.Duration
+
+		rsa    *rsa.PrivateKey
+		ec     *ecdsa.PrivateKey
+		secret []byte
+	}
+
+	// JWT is Credentials which authorize requests with a bearer token minted by
+	// the TokenFactory returned by Tokens. Declare the identity directly on the
+	// test case instead of generating tokens by hand:
+	//
+	//	tc := truth.TestCase{
+	//		Identity: truth.JWT{Sub: "42", Scopes: []string{"users:read"}},
+	//	}
+	JWT struct {
+		Sub      string
+		Scopes   []string
+		Audience string
+		// Expires is the lifetime of the token. A negative value mints a token which
+		// has already expired. Zero uses the TTL of the TokenFactory.
+		Expires time.Duration
+		// Claims are added to the token and take precedence over the fields above.
+		Claims Claims
+	}
+)
+
+var (
+	tokensMu sync.Mutex
+	tokens   *TokenFactory
+)
+
+// NewTokenFactory generates a key for the algorithm and returns a factory minting
+// tokens which expire after an hour.
+func NewTokenFactory(alg string) (*TokenFactory, error) {
+	f := &TokenFactory{
+		Alg:   alg,
+		KeyID: "truth-" + strings.ToLower(alg),
+		TTL:   time.Hour,
+	}
+
+	var err error
+	switch alg {
+	case JWTAlgRS256:
+		f.rsa, err = rsa.GenerateKey(rand.Reader, 2048)
+	case JWTAlgES256:
+		f.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JWTAlgHS256:
+		f.secret = make([]byte, 32)
+		_, err = rand.Read(f.secret)
+	default:
+		return nil, fmt.Errorf("JWT algorithm %#v is not supported", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SetTokenFactory sets the factory used to mint JWT identities.
+func SetTokenFactory(f *TokenFactory) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens = f
+}
+
+// Tokens returns the factory used to mint JWT identities. An RS256 factory is
+// created on first use unless one was provided with SetTokenFactory.
+func Tokens() (*TokenFactory, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	if tokens == nil {
+		f, err := NewTokenFactory(JWTAlgRS256)
+		if err != nil {
+			return nil, err
+		}
+		tokens = f
+	}
+
+	return tokens, nil
+}
+
+// Mint returns a signed token holding the claims. The `iss`, `aud`, `iat` and
+// `exp` claims are set unless provided or unless the factory has no value for
+// them. Provide an `exp` in the past to mint an expired token.
+func (f *TokenFactory) Mint(claims Claims) (string, error) {
+	now := time.Now()
+
+	c := Claims{
+		"iat": now.Unix(),
+		"exp": now.Add(f.TTL).Unix(),
+	}
+	if f.Issuer != "" {
+		c["iss"] = f.Issuer
+	}
+	if f.Audience != "" {
+		c["aud"] = f.Audience
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+
+	return f.Sign(c)
+}
+
+// Sign returns a token holding exactly the provided claims.
+func (f *TokenFactory) Sign(claims Claims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": f.Alg, "typ": "JWT", "kid": f.KeyID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	var sig []byte
+	switch {
+	case f.rsa != nil:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, f.rsa, crypto.SHA256, digest[:])
+	case f.ec != nil:
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, f.ec, digest[:]); err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	default:
+		mac := hmac.New(sha256.New, f.secret)
+		mac.Write([]byte(unsigned))
+		sig = mac.Sum(nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Secret returns the shared key of an HS256 factory.
+func (f *TokenFactory) Secret() []byte {
+	return f.secret
+}
+
+// PublicKey returns the public key of an RS256 or ES256 factory.
+func (f *TokenFactory) PublicKey() crypto.PublicKey {
+	switch {
+	case f.rsa != nil:
+		return &f.rsa.PublicKey
+	case f.ec != nil:
+		return &f.ec.PublicKey
+	}
+	return nil
+}
+
+// JWK returns the JSON Web Key the factory verifies with. An HS256 factory
+// publishes its secret so only use it in tests.
+func (f *TokenFactory) JWK() map[string]string {
+	key := map[string]string{
+		"alg": f.Alg,
+		"use": "sig",
+		"kid": f.KeyID,
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString
+
+	switch {
+	case f.rsa != nil:
+		key["kty"] = "RSA"
+		key["n"] = enc(f.rsa.N.Bytes())
+		key["e"] = enc(big.NewInt(int64(f.rsa.E)).Bytes())
+	case f.ec != nil:
+		x, y := make([]byte, 32), make([]byte, 32)
+		f.ec.X.FillBytes(x)
+		f.ec.Y.FillBytes(y)
+		key["kty"] = "EC"
+		key["crv"] = "P-256"
+		key["x"] = enc(x)
+		key["y"] = enc(y)
+	default:
+		key["kty"] = "oct"
+		key["k"] = enc(f.secret)
+	}
+
+	return key
+}
+
+// JWKS returns a handler serving the JSON Web Key Set of the factory. Point the
+// application under test at it to verify minted tokens.
+func (f *TokenFactory) JWKS() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeJSON(rw, http.StatusOK, map[string]interface{}{
+			"keys": []interface{}{f.JWK()},
+		})
+	})
+}
+
+// Authorize mints the token and sets the `Authorization` header.
+func (j JWT) Authorize(req *http.Request) error {
+	f, err := Tokens()
+	if err != nil {
+		return err
+	}
+
+	token, err := f.Mint(j.claims())
+	if err != nil {
+		return err
+	}
+
+	return Bearer(token).Authorize(req)
+}
+
+func (j JWT) claims() Claims {
+	c := Claims{}
+
+	if j.Sub != "" {
+		c["sub"] = j.Sub
+	}
+	if len(j.Scopes) > 0 {
+		c["scope"] = strings.Join(j.Scopes, " ")
+	}
+	if j.Audience != "" {
+		c["aud"] = j.Audience
+	}
+	if j.Expires != 0 {
+		c["exp"] = time.Now().Add(j.Expires).Unix()
+	}
+	for k, v := range j.Claims {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,146 @@
+package truth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenFactorySignatures(t *testing.T) {
+	for _, alg := range []string{JWTAlgRS256, JWTAlgES256, JWTAlgHS256} {
+		t.Run(alg, func(t *testing.T) {
+			f, err := NewTokenFactory(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Issuer, f.Audience = "https://issuer.example.com", "api"
+
+			token, err := f.Mint(Claims{"sub": "42"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header, claims := verifyJWT(t, f.JWK(), token)
+			if header["alg"] != alg || header["kid"] != f.KeyID {
+				t.Errorf("Unexpected header %v", header)
+			}
+			for k, v := range map[string]interface{}{"sub": "42", "iss": f.Issuer, "aud": f.Audience} {
+				if claims[k] != v {
+					t.Errorf("Expected claim %s to be %v but received %v", k, v, claims[k])
+				}
+			}
+			if exp, _ := claims["exp"].(float64); int64(exp) <= time.Now().Unix() {
+				t.Errorf("Expected the token to expire in the future but exp is %v", claims["exp"])
+			}
+
+			// A token whose claims were changed must not verify.
+			parts := strings.Split(token, ".")
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1"}`))
+			if verifySignature(f.JWK(), strings.Join(parts, ".")) {
+				t.Error("Expected a tampered token to fail verification")
+			}
+		})
+	}
+}
+
+func TestNewTokenFactoryRejectsUnknownAlgorithms(t *testing.T) {
+	if _, err := NewTokenFactory("none"); err == nil {
+		t.Error("Expected an error for the none algorithm")
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		jwt      JWT
+		expected Claims
+	}{
+		{name: "empty", jwt: JWT{}, expected: Claims{}},
+		{name: "subject and scopes", jwt: JWT{Sub: "42", Scopes: []string{"a", "b"}}, expected: Claims{"sub": "42", "scope": "a b"}},
+		{name: "audience", jwt: JWT{Audience: "api"}, expected: Claims{"aud": "api"}},
+		{name: "claims win", jwt: JWT{Sub: "42", Claims: Claims{"sub": "7", "role": "admin"}}, expected: Claims{"sub": "7", "role": "admin"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual, expected := JSON(test.jwt.claims()), JSON(test.expected); string(actual) != string(expected) {
+				t.Errorf("Expected %s but received %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestJWTExpires(t *testing.T) {
+	claims := JWT{Expires: -time.Minute}.claims()
+	if exp, _ := claims["exp"].(int64); exp >= time.Now().Unix() {
+		t.Errorf("Expected a token which has expired but exp is %v", claims["exp"])
+	}
+}
+
+// verifyJWT verifies the token with the published key and returns its header
+// and claims.
+func verifyJWT(t *testing.T, jwk map[string]string, token string) (map[string]interface{}, Claims) {
+	t.Helper()
+
+	if !verifySignature(jwk, token) {
+		t.Fatalf("Token %s does not verify with %v", token, jwk)
+	}
+
+	parts := strings.Split(token, ".")
+	var header map[string]interface{}
+	var claims Claims
+	for i, v := range []interface{}{&header, &claims} {
+		b, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return header, claims
+}
+
+func verifySignature(jwk map[string]string, token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	unsigned := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(unsigned))
+	number := func(k string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(jwk[k])
+		return new(big.Int).SetBytes(b)
+	}
+
+	switch jwk["kty"] {
+	case "RSA":
+		key := &rsa.PublicKey{N: number("n"), E: int(number("e").Int64())}
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case "EC":
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: number("x"), Y: number("y")}
+		return len(sig) == 64 && ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+	case "oct":
+		secret, _ := base64.RawURLEncoding.DecodeString(jwk["k"])
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(unsigned))
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+	return false
+}
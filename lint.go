@@ -0,0 +1,87 @@
+package truth
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// LintIssue describes a problem with an endpoint which makes it harder to
+	// document, test or consume.
+	LintIssue struct {
+		Endpoint string
+		Message  string
+	}
+)
+
+// String describes the issue.
+func (i LintIssue) String() string {
+	return i.Endpoint + ": " + i.Message
+}
+
+// Lint checks every endpoint of the snapshot for missing documentation, unknown
+// or ambiguous authentication, malformed paths, undescribed route variables and
+// duplicate routes.
+func Lint(s Snapshot) []LintIssue {
+	var issues []LintIssue
+
+	seen := map[string]bool{}
+
+	for _, e := range s.Endpoints {
+		add := func(format string, args ...interface{}) {
+			issues = append(issues, LintIssue{Endpoint: e.String(), Message: fmt.Sprintf(format, args...)})
+		}
+
+		if err := (&Definition{Method: e.Method, Path: e.Path}).Init(); err != nil {
+			add("%s", err)
+		}
+		if !strings.HasPrefix(e.Path, "/") {
+			add("Path must begin with a \"/\"")
+		}
+
+		if seen[e.key()] {
+			add("Route is defined more than once")
+		}
+		seen[e.key()] = true
+
+		if e.Name == "" {
+			add("Name is empty")
+		}
+		if e.Description == "" {
+			add("Description is empty")
+		}
+
+		switch e.Authentication {
+		case AuthorizationCredentials, AuthenticationChecksum, AuthorizationOpenID, AuthorizationNone:
+		case "":
+			if e.Authenticated {
+				add("Authenticated is set but Authentication does not say how")
+			} else {
+				add("Authentication is not specified. Use AuthorizationNone for public endpoints")
+			}
+		default:
+			add("Authentication %#v is unknown", e.Authentication)
+		}
+
+		if e.Authenticated && e.Authentication == AuthorizationNone {
+			add("Authenticated is set but Authentication is AuthorizationNone")
+		}
+
+		if e.InputParams != nil {
+			for _, p := range e.Params {
+				if e.InputParams.Properties[p] == nil {
+					add("Route variable %#v is not described by InputParams", p)
+				}
+			}
+		}
+
+		switch e.Method {
+		case GET, HEAD, DELETE, OPTIONS, TRACE:
+			if e.RequestBody != nil {
+				add("%s requests should not have a RequestBody", e.Method)
+			}
+		}
+	}
+
+	return issues
+}
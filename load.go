@@ -0,0 +1,182 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type (
+	// loadRequest is a request built from a test case for a load testing tool.
+	loadRequest struct {
+		Name     string            `json:"name"`
+		Method   string            `json:"method"`
+		URL      string            `json:"url"`
+		Headers  map[string]string `json:"headers,omitempty"`
+		Body     string            `json:"body,omitempty"`
+		Tag      string            `json:"tag"`
+		Status   int               `json:"status"`
+		Contains []string          `json:"contains,omitempty"`
+
+		body   []byte
+		header http.Header
+	}
+
+	// vegetaTarget is a target in the JSON format read by `vegeta attack
+	// -format=json`.
+	vegetaTarget struct {
+		Method string              `json:"method"`
+		URL    string              `json:"url"`
+		Body   []byte              `json:"body,omitempty"`
+		Header map[string][]string `json:"header,omitempty"`
+	}
+)
+
+// loadRequests builds the request of every test case with the client so the
+// requests carry the credentials the client attaches. Without a client the
+// client of in-process tests is used.
+func loadRequests(c *Client, entries []Entry) ([]loadRequest, error) {
+	if c == nil {
+		c = integrationClient
+	}
+
+	var requests []loadRequest
+
+	for _, e := range entries {
+		def := e.Definition
+
+		tag := def.StatsKey
+		if tag == "" {
+			tag = def.Method + " " + def.Path
+		}
+
+		for i, tc := range e.Cases {
+			req, err := c.BuildRequest(def, *tc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: Unable to build request: %s", tc.Name, err)
+			}
+			if req.URL.Host == "" {
+				return nil, fmt.Errorf("Load tests require a Client with a Hostname but `%s:%s` has none", def.Method, req.URL)
+			}
+
+			body, err := requestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			r := loadRequest{
+				Name:     tc.Name,
+				Method:   req.Method,
+				URL:      req.URL.String(),
+				Headers:  map[string]string{},
+				Body:     string(body),
+				Tag:      tag,
+				Status:   statusOf(tc),
+				Contains: tc.Contains,
+				body:     body,
+				header:   req.Header,
+			}
+			if r.Name == "" {
+				r.Name = fmt.Sprintf("%s #%d", tag, i+1)
+			}
+			for k, v := range req.Header {
+				r.Headers[k] = strings.Join(v, ", ")
+			}
+
+			requests = append(requests, r)
+		}
+	}
+
+	return requests, nil
+}
+
+// k6Script runs every request once per iteration and checks the status and
+// the terms the body contains. The first %s is replaced by what is tested and
+// the second by the requests.
+const k6Script = `// Load test of %s generated by truth. Run it with k6:
+//
+//	k6 run --vus 10 --duration 30s script.js
+import http from "k6/http";
+import { check } from "k6";
+
+export const options = {
+  thresholds: {
+    checks: ["rate==1.0"],
+  },
+};
+
+const requests = %s;
+
+export default function () {
+  for (const r of requests) {
+    const res = http.request(r.method, r.url, r.body || null, { headers: r.headers, tags: { name: r.tag } });
+
+    const checks = {};
+    checks[r.name + ": status is " + r.status] = (res) => res.status === r.status;
+    for (const term of r.contains || []) {
+      checks[r.name + ": body contains " + term] = (res) => typeof res.body === "string" && res.body.includes(term);
+    }
+    check(res, checks, { name: r.tag });
+  }
+}
+`
+
+// WriteK6 writes the test cases of the entries as a k6 script. Requests are
+// built by the client, which provides the host and credentials, and tagged with
+// the StatsKey of their Definition. Each response is checked for the status and
+// the terms the body contains and the script fails when a check does.
+//
+//	f, _ := os.Create("load.js")
+//	c := truth.NewClient("https://staging.example.com")
+//	err := truth.WriteK6(f, c, []truth.Entry{{Definition: getUsersDef, Cases: getUsersCases}})
+//
+// Credentials are written into the script as they are attached by the client.
+// Signatures made for AuthenticationChecksum expire with the Skew of the Signer.
+func WriteK6(w io.Writer, c *Client, entries []Entry) error {
+	requests, err := loadRequests(c, entries)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, k6Script, loadSubject(entries), b)
+	return err
+}
+
+// WriteVegeta writes the test cases of the entries as Vegeta targets in the
+// JSON format, one target per line:
+//
+//	vegeta attack -format=json -targets=targets.json -rate=50 -duration=30s | vegeta report
+//
+// Requests are built as they are by WriteK6. Vegeta does not check responses
+// so compare the status codes of its report with those of the test cases.
+func WriteVegeta(w io.Writer, c *Client, entries []Entry) error {
+	requests, err := loadRequests(c, entries)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, r := range requests {
+		t := vegetaTarget{Method: r.Method, URL: r.URL, Body: r.body, Header: r.header}
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadSubject describes the Definitions of the entries.
+func loadSubject(entries []Entry) string {
+	if len(entries) == 1 {
+		return fmt.Sprintf("`%s %s`", entries[0].Definition.Method, entries[0].Definition.Path)
+	}
+	return fmt.Sprintf("%d endpoints", len(entries))
+}
@@ -0,0 +1,124 @@
+package truth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// loadEntries are a listing with a query string and a creation with a body.
+func loadEntries() []Entry {
+	return []Entry{
+		{
+			Definition: Definition{Method: "GET", Path: "/users", StatsKey: "users.list", MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON, Authentication: AuthorizationCredentials},
+			Cases:      TestCases{{Path: "/users?page=2&sort=name", Contains: []string{`"id"`}}},
+		},
+		{
+			Definition: Definition{Method: "POST", Path: "/users", MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON},
+			Cases:      TestCases{{Name: "create", Path: "/users", Payload: map[string]string{"name": "Ann"}, Status: 201}},
+		},
+	}
+}
+
+func loadClient() *Client {
+	c := NewClient("https://api.example.com")
+	c.BasePath = "/v2"
+	c.SetCredentials(AuthorizationCredentials, Bearer("token"))
+	return c
+}
+
+func TestLoadRequests(t *testing.T) {
+	requests, err := loadRequests(loadClient(), loadEntries())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"name":"users.list #1","method":"GET","url":"https://api.example.com/v2/users?page=2\u0026sort=name","headers":{"Authorization":"Bearer token"},"tag":"users.list","status":200,"contains":["\"id\""]},` +
+		`{"name":"create","method":"POST","url":"https://api.example.com/v2/users","body":"{\"name\":\"Ann\"}","tag":"POST /users","status":201}]`
+	if actual := string(JSON(requests)); actual != expected {
+		t.Errorf("Expected %s but received %s", expected, actual)
+	}
+	if body := string(requests[1].body); body != `{"name":"Ann"}` {
+		t.Errorf("Expected the body to be kept for Vegeta but received %s", body)
+	}
+
+	withClient(t, NewClient(""))
+	if _, err := loadRequests(nil, loadEntries()); err == nil || !strings.Contains(err.Error(), "Load tests require a Client with a Hostname") {
+		t.Errorf("Expected an error without a Hostname but received %v", err)
+	}
+}
+
+func TestWriteK6(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteK6(&b, loadClient(), loadEntries()); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := b.String(); actual != k6Golden {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", k6Golden, actual)
+	}
+}
+
+const k6Golden = `// Load test of 2 endpoints generated by truth. Run it with k6:
+//
+//	k6 run --vus 10 --duration 30s script.js
+import http from "k6/http";
+import { check } from "k6";
+
+export const options = {
+  thresholds: {
+    checks: ["rate==1.0"],
+  },
+};
+
+const requests = [
+  {
+    "name": "users.list #1",
+    "method": "GET",
+    "url": "https://api.example.com/v2/users?page=2\u0026sort=name",
+    "headers": {
+      "Authorization": "Bearer token"
+    },
+    "tag": "users.list",
+    "status": 200,
+    "contains": [
+      "\"id\""
+    ]
+  },
+  {
+    "name": "create",
+    "method": "POST",
+    "url": "https://api.example.com/v2/users",
+    "body": "{\"name\":\"Ann\"}",
+    "tag": "POST /users",
+    "status": 201
+  }
+];
+
+export default function () {
+  for (const r of requests) {
+    const res = http.request(r.method, r.url, r.body || null, { headers: r.headers, tags: { name: r.tag } });
+
+    const checks = {};
+    checks[r.name + ": status is " + r.status] = (res) => res.status === r.status;
+    for (const term of r.contains || []) {
+      checks[r.name + ": body contains " + term] = (res) => typeof res.body === "string" && res.body.includes(term);
+    }
+    check(res, checks, { name: r.tag });
+  }
+}
+`
+
+func TestWriteVegeta(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteVegeta(&b, loadClient(), loadEntries()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"method":"GET","url":"https://api.example.com/v2/users?page=2\u0026sort=name","header":{"Authorization":["Bearer token"]}}
+{"method":"POST","url":"https://api.example.com/v2/users","body":"eyJuYW1lIjoiQW5uIn0="}
+`
+	if actual := b.String(); actual != expected {
+		t.Errorf("Expected:\n%s\nReceived:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,199 @@
+package truth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Mock is an http.Handler which answers requests using Definitions and their
+// test cases so teams can develop against an API before it is built. Requests
+// are routed by the Method and Path of each Definition. A request is answered
+// with the Status and ExpectBody of the test case which best matches it or, when
+// no test case provides a body, with an example synthesized from the
+// Definition's ResponseBody. A test case without a body expecting a status
+// other than 2XX is answered with a JSON error instead.
+//
+// Requests which do not satisfy the Definition's RequestBody are answered with
+// `400 Bad Request` and a JSON error describing the misuse.
+type Mock struct {
+	entries []Entry
+}
+
+// NewMock returns a Mock answering for the entries. Use Registered to mock every
+// registered Definition:
+//
+//	http.ListenAndServe(":8080", truth.NewMock(truth.Registered()))
+func NewMock(entries []Entry) *Mock {
+	m := &Mock{entries: append([]Entry(nil), entries...)}
+
+	// Prefer static routes over routes with variables.
+	sort.SliceStable(m.entries, func(i, j int) bool {
+		return len(pathParams(m.entries[i].Definition.Path)) < len(pathParams(m.entries[j].Definition.Path))
+	})
+
+	return m
+}
+
+// ListenAndServeMock serves a Mock of every registered Definition on the address.
+func ListenAndServeMock(addr string) error {
+	return http.ListenAndServe(addr, NewMock(Registered()))
+}
+
+// ServeHTTP answers the request.
+func (m *Mock) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	e, allowed := m.route(req)
+	if e == nil {
+		if len(allowed) > 0 {
+			rw.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeJSON(rw, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+			return
+		}
+		writeJSON(rw, http.StatusNotFound, map[string]string{"error": "No Definition matches the request"})
+		return
+	}
+
+	def := e.Definition
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			writeJSON(rw, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	if def.RequestBody.Data != nil {
+		err := def.RequestBody.Validate(body)
+		if len(body) == 0 {
+			err = errEmptyBody
+		}
+		if err != nil {
+			writeJSON(rw, http.StatusBadRequest, map[string]string{
+				"error":      err.Error(),
+				"definition": def.Name,
+			})
+			return
+		}
+	}
+
+	for k, v := range def.ResponseHeaders {
+		rw.Header().Set(k, v)
+	}
+
+	tc := bestCase(e.Cases, req, body)
+	if tc != nil && tc.ExpectBody != nil {
+		if def.MIMETypeResponse != "" {
+			rw.Header().Set("Content-Type", def.MIMETypeResponse)
+		}
+		rw.WriteHeader(statusOf(tc))
+		rw.Write(tc.ExpectBody)
+		return
+	}
+
+	status := http.StatusOK
+	if tc != nil {
+		status = statusOf(tc)
+	}
+
+	switch {
+	case status < 200 || status > 299:
+		// The ResponseBody describes a successful response.
+		writeJSON(rw, status, map[string]string{"error": http.StatusText(status)})
+	case def.ResponseBody.Data == nil:
+		rw.WriteHeader(status)
+	default:
+		writeJSON(rw, status, Example(def.ResponseBody.Data))
+	}
+}
+
+var errEmptyBody = errors.New("Request body is empty")
+
+// route returns the entry matching the request. When no entry matches, the
+// methods allowed for the path are returned.
+func (m *Mock) route(req *http.Request) (*Entry, []string) {
+	var allowed []string
+
+	for i, e := range m.entries {
+		if _, ok := matchPath(e.Definition.Path, req.URL.Path); !ok {
+			continue
+		}
+		if e.Definition.Method == req.Method {
+			return &m.entries[i], nil
+		}
+		allowed = append(allowed, e.Definition.Method)
+	}
+
+	return nil, allowed
+}
+
+// bestCase scores the test cases against the request. An exact match on the path
+// and query string, and a payload equal to the request body, are preferred. Ties
+// favor successful test cases and then the order the cases were registered.
+func bestCase(cases TestCases, req *http.Request, body []byte) *TestCase {
+	var best *TestCase
+	bestScore := -1
+
+	for _, tc := range cases {
+		score := 0
+
+		if tc.Path == req.URL.RequestURI() {
+			score += 4
+		} else if p, _ := splitPath(tc.Path); p == req.URL.Path {
+			score += 2
+		}
+
+		if tc.Payload != nil && len(body) > 0 {
+			if payload, err := marshalPayload(tc.Payload); err == nil && jsonEqual(payload, body) {
+				score += 4
+			}
+		}
+
+		if s := statusOf(tc); s >= 200 && s < 300 {
+			score++
+		}
+
+		if score > bestScore {
+			best, bestScore = tc, score
+		}
+	}
+
+	return best
+}
+
+// Example returns an example of a body shaped like v, which may be a *Schema, so
+// it can be serialized. See Schema.Example.
+func Example(v interface{}) interface{} {
+	return SchemaOf(v).Example()
+}
+
+// splitPath separates the query string from the path.
+func splitPath(path string) (string, string) {
+	if i := strings.Index(path, "?"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// jsonEqual reports if both documents hold the same JSON value. Documents which
+// are not JSON are compared byte for byte.
+func jsonEqual(a, b []byte) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func statusOf(tc *TestCase) int {
+	if tc.Status == 0 {
+		return http.StatusOK
+	}
+	return tc.Status
+}
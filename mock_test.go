@@ -0,0 +1,136 @@
+package truth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockUser struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Tags    []string  `json:"tags,omitempty"`
+	Manager *mockUser `json:"manager,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func TestMock(t *testing.T) {
+	entries := []Entry{
+		{
+			Definition: Definition{Method: "GET", Path: "/users/{id}", MIMETypeResponse: MIMETypeJSON, ResponseBody: BodyDefinition{Data: mockUser{}}},
+			Cases: TestCases{
+				{Name: "found", Path: "/users/1", ExpectBody: []byte(`{"id":1,"name":"Ann"}`)},
+				{Name: "missing", Path: "/users/2", Status: 404},
+			},
+		},
+		{
+			Definition: Definition{Method: "GET", Path: "/users/me", ResponseHeaders: map[string]string{"Cache-Control": "no-store"}},
+			Cases:      TestCases{{Name: "me", Path: "/users/me", Status: 204}},
+		},
+		{
+			Definition: Definition{Method: "POST", Path: "/users", RequestBody: BodyDefinition{Data: mockUser{}}, ResponseBody: BodyDefinition{Data: mockUser{}}},
+			Cases:      TestCases{{Name: "create", Path: "/users", Status: 201}},
+		},
+	}
+
+	m := NewMock(entries)
+	if entries[0].Definition.Path != "/users/{id}" {
+		t.Errorf("Expected the entries of the caller to keep their order but received %s first", entries[0].Definition.Path)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		expected string
+		header   string
+	}{
+		{name: "test case body", method: "GET", path: "/users/1", status: 200, expected: `{"id":1,"name":"Ann"}`},
+		{name: "closest test case", method: "GET", path: "/users/3", status: 200, expected: `{"id":1,"name":"Ann"}`},
+		{name: "error without body", method: "GET", path: "/users/2", status: 404, expected: `{"error":"Not Found"}`},
+		{name: "static route first", method: "GET", path: "/users/me", status: 204, header: "no-store"},
+		{name: "example", method: "POST", path: "/users", body: `{"id":1,"name":"Ann","created":"2016-04-01T12:00:00Z"}`, status: 201, expected: `{"created":"2016-04-01T12:00:00Z","id":1,"manager":{},"name":"string","tags":["string"]}`},
+		{name: "empty request body", method: "POST", path: "/users", status: 400},
+		{name: "invalid request body", method: "POST", path: "/users", body: `{"unknown":true}`, status: 400},
+		{name: "method not allowed", method: "DELETE", path: "/users", status: 405, expected: `{"error":"Method not allowed"}`},
+		{name: "not found", method: "GET", path: "/orders", status: 404},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			RR := httptest.NewRecorder()
+			m.ServeHTTP(RR, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+			if RR.Code != test.status {
+				t.Errorf("Expected statuscode %d but received %d: %s", test.status, RR.Code, RR.Body.String())
+			}
+			if test.expected != "" && !jsonEqual([]byte(test.expected), RR.Body.Bytes()) {
+				t.Errorf("Expected %s but received %s", test.expected, RR.Body.String())
+			}
+			if test.header != "" && RR.Header().Get("Cache-Control") != test.header {
+				t.Errorf("Expected the ResponseHeaders but received %v", RR.Header())
+			}
+		})
+	}
+}
+
+func TestBestCase(t *testing.T) {
+	cases := TestCases{
+		{Name: "list", Path: "/users"},
+		{Name: "page", Path: "/users?page=2"},
+		{Name: "invalid", Path: "/users", Payload: map[string]string{"name": ""}, Status: 400},
+		{Name: "create", Path: "/users", Payload: map[string]string{"name": "Ann"}, Status: 201},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected string
+	}{
+		{name: "path", path: "/users", expected: "list"},
+		{name: "query", path: "/users?page=2", expected: "page"},
+		{name: "payload", path: "/users", body: `{"name": ""}`, expected: "invalid"},
+		{name: "successful tie", path: "/users", body: `{"name":"Bob"}`, expected: "list"},
+		{name: "other path", path: "/other", expected: "list"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", test.path, nil)
+			if tc := bestCase(cases, req, []byte(test.body)); tc == nil || tc.Name != test.expected {
+				t.Errorf("Expected %s but received %v", test.expected, tc)
+			}
+		})
+	}
+
+	if tc := bestCase(nil, httptest.NewRequest("GET", "/", nil), nil); tc != nil {
+		t.Errorf("Expected no test case but received %v", tc)
+	}
+}
+
+func TestExample(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        interface{}
+		expected string
+	}{
+		{name: "nil", v: nil, expected: `null`},
+		{name: "string", v: "", expected: `"string"`},
+		{name: "map", v: map[string]int{}, expected: `{"key":1}`},
+		{name: "slice", v: []bool{}, expected: `[true]`},
+		{name: "schema", v: &Schema{Type: "object", Properties: map[string]*Schema{"id": {Type: "integer"}}}, expected: `{"id":1}`},
+		{name: "recursive", v: schemaNode{}, expected: string(JSON(SchemaOf(schemaNode{}).Example()))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := JSON(Example(test.v)); !jsonEqual(actual, []byte(test.expected)) {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
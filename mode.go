@@ -0,0 +1,158 @@
+package truth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+const (
+	// ModeInProcess calls the mux under test directly without a network.
+	ModeInProcess = "inprocess"
+	// ModeServer serves the mux under test with an httptest.Server and sends
+	// every request over TCP.
+	ModeServer = "server"
+	// ModeTLS is ModeServer over TLS.
+	ModeTLS = "tls"
+	// ModeRemote sends every request to the server of the `-truth.host` flag or
+	// of the Environment selected by the `-truth.env` flag.
+	ModeRemote = "remote"
+	// ModeParity runs every test case in-process and over the wire, reporting
+	// any difference between the responses. Requests go over the wire as in
+	// ModeRemote when a host or environment is selected and as in ModeServer
+	// otherwise. See IgnoreParityHeaders.
+	ModeParity = "parity"
+)
+
+var (
+	modeMu     sync.Mutex
+	modeServer *httptest.Server
+)
+
+// SetMode sets the mode of the package, overriding the `-truth.mode` flag. The
+// mode applies to every test run without a Client so a whole package switches
+// between modes with a flag:
+//
+//	go test ./... -truth.mode=server
+func SetMode(m string) {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	mode = m
+}
+
+// Mode returns the mode of the package. Without a mode, tests run remotely when
+// a host or environment is selected and in-process otherwise.
+func Mode() string {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	return currentMode()
+}
+
+func currentMode() string {
+	if mode != "" {
+		return mode
+	}
+	if hostFlag != "" || envFlag != "" {
+		return ModeRemote
+	}
+	return ModeInProcess
+}
+
+// modeClient returns the Client tests run without a Client use. It is nil in
+// ModeInProcess and points at the selected Environment in ModeRemote.
+// Otherwise the mux under test is served, once, and the Client is pointed at
+// the server with the credentials of the package.
+func modeClient() (*Client, error) {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	m := currentMode()
+	switch m {
+	case ModeInProcess:
+		return nil, nil
+	case ModeRemote:
+		return remoteClient()
+	case ModeParity:
+		if hostFlag != "" || envFlag != "" {
+			return remoteClient()
+		}
+		m = ModeServer
+	case ModeServer, ModeTLS:
+	default:
+		return nil, fmt.Errorf("Unknown mode %#v", m)
+	}
+
+	if muxUnderTest == nil {
+		return nil, errNoMux
+	}
+
+	tls := m == ModeTLS
+	if modeServer != nil && (modeServer.TLS != nil) != tls {
+		modeServer.Close()
+		modeServer = nil
+	}
+
+	if modeServer == nil {
+		// The mux is looked up for every request as tests may call SetMux again.
+		h := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			muxUnderTest.ServeHTTP(rw, req)
+		})
+		if tls {
+			modeServer = httptest.NewTLSServer(h)
+		} else {
+			modeServer = httptest.NewServer(h)
+		}
+	}
+
+	c := *integrationClient
+	c.Hostname = modeServer.URL
+	c.HTTPClient = modeServer.Client()
+
+	return &c, nil
+}
+
+// remoteClient returns a Client for the selected Environment with the
+// credentials of the package and of the Environment.
+func remoteClient() (*Client, error) {
+	env, err := environment()
+	if err != nil {
+		return nil, err
+	}
+	if env.Host == "" {
+		return nil, fmt.Errorf("Mode %#v requires the -truth.host flag or an environment with a Host", ModeRemote)
+	}
+
+	c := *integrationClient
+	c.Hostname = env.Host
+	c.BasePath = env.BasePath
+	c.Credentials = mergeCredentials(integrationClient.Credentials, env.Credentials)
+	c.Identities = mergeCredentials(integrationClient.Identities, env.Identities)
+
+	return &c, nil
+}
+
+func mergeCredentials(a, b map[string]Credentials) map[string]Credentials {
+	out := make(map[string]Credentials, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		out[k] = v
+	}
+	return out
+}
+
+// CloseServer closes the server started for ModeServer or ModeTLS. Call it from
+// TestMain once the tests have run.
+func CloseServer() {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+
+	if modeServer != nil {
+		modeServer.Close()
+		modeServer = nil
+	}
+}
@@ -0,0 +1,153 @@
+package truth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// withMode runs the test in the mode against the mux and restores the mode,
+// host and mux of the package afterwards, closing any server it started.
+func withMode(t *testing.T, m, host string, mux http.Handler) {
+	t.Cleanup(func(m, host string, mux http.Handler) func() {
+		return func() {
+			CloseServer()
+			SetMode(m)
+			SetHost(host)
+			SetMux(mux)
+		}
+	}(mode, hostFlag, muxUnderTest))
+
+	SetMode(m)
+	SetHost(host)
+	SetMux(mux)
+}
+
+func TestModes(t *testing.T) {
+	var received []string
+
+	// The mux tells how the request reached it.
+	mux := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.TLS != nil:
+			received = append(received, "tls")
+		case req.RemoteAddr != "":
+			received = append(received, "tcp")
+		default:
+			received = append(received, "inprocess")
+		}
+		rw.Write([]byte(`{"id":1}`))
+	})
+
+	remote := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = append(received, "remote")
+		rw.Write([]byte(`{"id":1}`))
+	}))
+	defer remote.Close()
+
+	tests := []struct {
+		name     string
+		mode     string
+		host     string
+		expected []string
+		server   bool
+	}{
+		{name: "default", expected: []string{"inprocess"}},
+		{name: "default with host", host: remote.URL, expected: []string{"remote"}},
+		{name: "in-process", mode: ModeInProcess, host: remote.URL, expected: []string{"inprocess"}},
+		{name: "server", mode: ModeServer, expected: []string{"tcp"}, server: true},
+		{name: "tls", mode: ModeTLS, expected: []string{"tls"}, server: true},
+		{name: "remote", mode: ModeRemote, host: remote.URL, expected: []string{"remote"}},
+		{name: "parity", mode: ModeParity, expected: []string{"tcp", "inprocess"}, server: true},
+		{name: "parity with host", mode: ModeParity, host: remote.URL, expected: []string{"remote", "inprocess"}},
+	}
+
+	def := Definition{Method: "GET", Path: "/items", MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withMode(t, test.mode, test.host, mux)
+			received = nil
+
+			tc := TestCase{Name: test.name, Path: "/items", ExpectBody: []byte(`{"id":1}`)}
+			tc.alias = tc.Name
+			if err := NewRunner(nil)(t, def, tc); err != nil {
+				t.Fatal(err)
+			}
+
+			if string(JSON(received)) != string(JSON(test.expected)) {
+				t.Errorf("Expected the requests %v but received %v", test.expected, received)
+			}
+
+			modeMu.Lock()
+			server := modeServer
+			modeMu.Unlock()
+
+			if (server != nil) != test.server {
+				t.Fatalf("Expected a server to be started to be %t", test.server)
+			}
+			if server == nil {
+				return
+			}
+
+			CloseServer()
+			if modeServer != nil {
+				t.Error("Expected the server to be forgotten once closed")
+			}
+			if _, err := server.Client().Get(server.URL); err == nil {
+				t.Error("Expected the server to be torn down")
+			}
+		})
+	}
+}
+
+func TestModeSwitchesServer(t *testing.T) {
+	withMode(t, ModeServer, "", http.NotFoundHandler())
+
+	c, err := modeClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := modeServer
+
+	if again, _ := modeClient(); again.Hostname != c.Hostname {
+		t.Errorf("Expected the server to be reused but received %s and %s", c.Hostname, again.Hostname)
+	}
+
+	SetMode(ModeTLS)
+	if c, err = modeClient(); err != nil {
+		t.Fatal(err)
+	}
+	if modeServer == plain || modeServer.TLS == nil {
+		t.Errorf("Expected a TLS server to replace the server but received %s", c.Hostname)
+	}
+	if _, err := plain.Client().Get(plain.URL); err == nil {
+		t.Error("Expected the replaced server to be torn down")
+	}
+}
+
+func TestModeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		mux      http.Handler
+		expected string
+	}{
+		{name: "unknown mode", mode: "cluster", mux: http.NotFoundHandler(), expected: `Unknown mode "cluster"`},
+		{name: "remote without host", mode: ModeRemote, mux: http.NotFoundHandler(), expected: `Mode "remote" requires the -truth.host flag or an environment with a Host`},
+		{name: "server without mux", mode: ModeServer, expected: errNoMux.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withMode(t, test.mode, "", test.mux)
+
+			if _, err := modeClient(); err == nil || err.Error() != test.expected {
+				t.Errorf("Expected %s but received %v", test.expected, err)
+			}
+			if modeServer != nil {
+				t.Error("Expected no server to be started")
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenIDProvider is an in-process OpenID Connect provider standing in for a real
+// identity provider while testing Definitions using AuthorizationOpenID. The
+// provider is served by an httptest.Server and exposes:
+//
+//	GET  /.well-known/openid-configuration
+//	GET  /jwks
+//	GET  /authorize
+//	POST /token
+//
+// Point the application under test at the provider's Issuer so it validates the
+// tokens minted here using its real validation code:
+//
+//	idp, err := truth.NewOpenIDProvider("my-api")
+//	defer idp.Close()
+//
+//	configureApp(idp.Issuer)
+//	truth.SetCredentials(truth.AuthorizationOpenID, idp.Credentials(truth.Claims{"sub": "42"}))
+type OpenIDProvider struct {
+	*httptest.Server
+
+	// Issuer is the URL of the provider and the `iss` claim of every token.
+	Issuer string
+	// Audience is the `aud` claim of tokens which do not provide one.
+	Audience string
+	// TTL is the lifetime of tokens which do not provide an `exp` claim.
+	TTL time.Duration
+
+	tokens *TokenFactory
+}
+
+type openIDCredentials struct {
+	provider *OpenIDProvider
+	claims   Claims
+}
+
+// NewOpenIDProvider generates a signing key and starts a provider minting tokens
+// for the audience. Call Close when finished.
+func NewOpenIDProvider(audience string) (*OpenIDProvider, error) {
+	tokens, err := NewTokenFactory(JWTAlgRS256)
+	if err != nil {
+		return nil, err
+	}
+	tokens.KeyID = "truth-openid"
+
+	p := &OpenIDProvider{
+		Audience: audience,
+		TTL:      time.Hour,
+		tokens:   tokens,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.onDiscovery)
+	mux.Handle("/jwks", tokens.JWKS())
+	mux.HandleFunc("/authorize", p.onAuthorize)
+	mux.HandleFunc("/token", p.onToken)
+
+	p.Server = httptest.NewServer(mux)
+	p.Issuer = p.Server.URL
+
+	return p, nil
+}
+
+// Mint returns a signed token holding the claims. The `iss`, `aud`, `iat` and
+// `exp` claims are set unless provided. Provide an `exp` in the past to mint an
+// expired token.
+func (p *OpenIDProvider) Mint(claims Claims) (string, error) {
+	f := *p.tokens
+	f.Issuer, f.Audience, f.TTL = p.Issuer, p.Audience, p.TTL
+	return f.Mint(claims)
+}
+
+// idToken mints an ID token for the subject. Its audience is the client the
+// token is issued to, or the Audience without one, and it carries the nonce of
+// the request when one was sent.
+func (p *OpenIDProvider) idToken(sub interface{}, clientID, nonce string) (string, error) {
+	claims := Claims{"sub": sub}
+	if clientID != "" {
+		claims["aud"] = clientID
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return p.Mint(claims)
+}
+
+// Credentials returns Credentials which authorize each request with a freshly
+// minted bearer token holding the claims.
+func (p *OpenIDProvider) Credentials(claims Claims) Credentials {
+	return openIDCredentials{provider: p, claims: claims}
+}
+
+func (c openIDCredentials) Authorize(req *http.Request) error {
+	token, err := c.provider.Mint(c.claims)
+	if err != nil {
+		return err
+	}
+	return Bearer(token).Authorize(req)
+}
+
+func (p *OpenIDProvider) onDiscovery(rw http.ResponseWriter, req *http.Request) {
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"token_endpoint":                        p.Issuer + "/token",
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"response_types_supported":              []string{"token", "id_token"},
+		"subject_types_supported":               []string{"public"},
+		"grant_types_supported":                 []string{"client_credentials", "password"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// onAuthorize approves every request of the implicit flow without a login
+// page. The subject is read from the `login_hint` or `sub` query values and the
+// tokens are returned in the fragment of the `redirect_uri`. The audience of an
+// ID token is the `client_id` and it carries the `nonce`.
+func (p *OpenIDProvider) onAuthorize(rw http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	fragment := url.Values{}
+	if state := q.Get("state"); state != "" {
+		fragment.Set("state", state)
+	}
+
+	respond := func() {
+		redirect.Fragment = fragment.Encode()
+		http.Redirect(rw, req, redirect.String(), http.StatusFound)
+	}
+
+	types := map[string]bool{}
+	for _, t := range strings.Fields(q.Get("response_type")) {
+		if t != "token" && t != "id_token" {
+			types = nil
+			break
+		}
+		types[t] = true
+	}
+	if len(types) == 0 {
+		fragment.Set("error", "unsupported_response_type")
+		respond()
+		return
+	}
+
+	claims := Claims{}
+	for _, k := range []string{"sub", "login_hint"} {
+		if v := q.Get(k); v != "" {
+			claims["sub"] = v
+		}
+	}
+	if _, ok := claims["sub"]; !ok {
+		fragment.Set("error", "login_required")
+		respond()
+		return
+	}
+	if v := q.Get("scope"); v != "" {
+		claims["scope"] = strings.TrimSpace(v)
+	}
+
+	if types["token"] {
+		token, err := p.Mint(claims)
+		if err != nil {
+			fragment.Set("error", "server_error")
+			respond()
+			return
+		}
+		fragment.Set("access_token", token)
+		fragment.Set("token_type", "Bearer")
+		fragment.Set("expires_in", fmt.Sprint(int(p.TTL.Seconds())))
+	}
+	if types["id_token"] {
+		token, err := p.idToken(claims["sub"], q.Get("client_id"), q.Get("nonce"))
+		if err != nil {
+			fragment = url.Values{"error": {"server_error"}}
+			respond()
+			return
+		}
+		fragment.Set("id_token", token)
+	}
+	respond()
+}
+
+// onToken issues tokens for any caller. The subject is read from the `sub`,
+// `username` or `client_id` form values. The `scope` and `audience` form values
+// are copied into the claims when provided. An ID token is issued as well for
+// the openid scope.
+func (p *OpenIDProvider) onToken(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeJSON(rw, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if err := req.ParseForm(); err != nil {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	claims := Claims{}
+	for _, k := range []string{"client_id", "username", "sub"} {
+		if v := req.PostForm.Get(k); v != "" {
+			claims["sub"] = v
+		}
+	}
+	if _, ok := claims["sub"]; !ok {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if v := req.PostForm.Get("scope"); v != "" {
+		claims["scope"] = strings.TrimSpace(v)
+	}
+	if v := req.PostForm.Get("audience"); v != "" {
+		claims["aud"] = v
+	}
+
+	token, err := p.Mint(claims)
+	if err != nil {
+		writeJSON(rw, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	body := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(p.TTL.Seconds()),
+	}
+
+	// An ID token is only issued for the openid scope.
+	for _, scope := range strings.Fields(req.PostForm.Get("scope")) {
+		if scope != "openid" {
+			continue
+		}
+		if body["id_token"], err = p.idToken(claims["sub"], req.PostForm.Get("client_id"), req.PostForm.Get("nonce")); err != nil {
+			writeJSON(rw, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, body)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", MIMETypeJSON)
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
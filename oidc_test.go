@@ -0,0 +1,275 @@
+package truth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenIDProviderDiscovery(t *testing.T) {
+	p, err := NewOpenIDProvider("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	var doc map[string]interface{}
+	getJSON(t, p.Issuer+"/.well-known/openid-configuration", &doc)
+	for k, v := range map[string]string{"issuer": p.Issuer, "jwks_uri": p.Issuer + "/jwks", "token_endpoint": p.Issuer + "/token", "authorization_endpoint": p.Issuer + "/authorize"} {
+		if doc[k] != v {
+			t.Errorf("Expected %s to be %s but received %v", k, v, doc[k])
+		}
+	}
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	getJSON(t, doc["jwks_uri"].(string), &jwks)
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("Expected one published key but received %v", jwks.Keys)
+	}
+
+	token, err := p.Mint(Claims{"sub": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, claims := verifyJWT(t, jwks.Keys[0], token)
+	if header["kid"] != jwks.Keys[0]["kid"] {
+		t.Errorf("Expected the token to name the published key but received %v", header)
+	}
+	for k, v := range map[string]interface{}{"sub": "42", "iss": p.Issuer, "aud": "api"} {
+		if claims[k] != v {
+			t.Errorf("Expected claim %s to be %v but received %v", k, v, claims[k])
+		}
+	}
+}
+
+func TestOpenIDProviderMint(t *testing.T) {
+	p, err := NewOpenIDProvider("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	p.TTL = time.Minute
+
+	tests := []struct {
+		name     string
+		claims   Claims
+		expected Claims
+	}{
+		{name: "defaults", claims: Claims{"sub": "42"}, expected: Claims{"sub": "42", "aud": "api"}},
+		{name: "claims win", claims: Claims{"sub": "42", "aud": "other", "iss": "https://other"}, expected: Claims{"aud": "other", "iss": "https://other"}},
+		{name: "expired", claims: Claims{"sub": "42", "exp": float64(1)}, expected: Claims{"exp": float64(1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := p.Mint(test.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, claims := verifyJWT(t, p.tokens.JWK(), token)
+			for k, v := range test.expected {
+				if claims[k] != v {
+					t.Errorf("Expected claim %s to be %v but received %v", k, v, claims[k])
+				}
+			}
+			if _, ok := test.claims["exp"]; !ok {
+				if exp, _ := claims["exp"].(float64); int64(exp) > time.Now().Add(p.TTL).Unix() {
+					t.Errorf("Expected the token to expire after the TTL but exp is %v", claims["exp"])
+				}
+			}
+		})
+	}
+}
+
+func TestOpenIDProviderToken(t *testing.T) {
+	p, err := NewOpenIDProvider("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		name    string
+		method  string
+		form    url.Values
+		status  int
+		sub     string
+		idToken bool
+	}{
+		{name: "client credentials", method: "POST", form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"web"}}, status: 200, sub: "web"},
+		{name: "password", method: "POST", form: url.Values{"grant_type": {"password"}, "username": {"ann"}, "scope": {"read"}}, status: 200, sub: "ann"},
+		{name: "openid", method: "POST", form: url.Values{"client_id": {"web"}, "username": {"ann"}, "scope": {"openid profile"}, "nonce": {"n-1"}}, status: 200, sub: "ann", idToken: true},
+		{name: "without subject", method: "POST", form: url.Values{"grant_type": {"client_credentials"}}, status: 400},
+		{name: "get", method: "GET", status: 405},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(test.method, p.Issuer+"/token", strings.NewReader(test.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rsp.Body.Close()
+
+			if rsp.StatusCode != test.status {
+				t.Fatalf("Expected statuscode %d but received %d", test.status, rsp.StatusCode)
+			}
+			if test.status != 200 {
+				return
+			}
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(rsp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			access, _ := body["access_token"].(string)
+			_, claims := verifyJWT(t, p.tokens.JWK(), access)
+			if claims["sub"] != test.sub || claims["aud"] != "api" || claims["nonce"] != nil {
+				t.Errorf("Unexpected access token claims %v", claims)
+			}
+
+			id, ok := body["id_token"].(string)
+			if ok != test.idToken {
+				t.Fatalf("Expected an ID token to be %t but received %v", test.idToken, body)
+			}
+			if ok {
+				_, claims := verifyJWT(t, p.tokens.JWK(), id)
+				if id == access || claims["sub"] != test.sub || claims["aud"] != "web" || claims["nonce"] != "n-1" || claims["scope"] != nil {
+					t.Errorf("Unexpected ID token claims %v", claims)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenIDProviderAuthorize(t *testing.T) {
+	p, err := NewOpenIDProvider("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		fragment []string
+		omitted  []string
+	}{
+		{
+			name:     "access token",
+			query:    url.Values{"response_type": {"token"}, "redirect_uri": {"https://app/cb"}, "login_hint": {"ann"}, "state": {"s-1"}},
+			status:   302,
+			fragment: []string{"access_token", "token_type", "expires_in", "state"},
+			omitted:  []string{"id_token", "error"},
+		},
+		{
+			name:     "id token",
+			query:    url.Values{"response_type": {"id_token token"}, "redirect_uri": {"https://app/cb"}, "sub": {"ann"}, "client_id": {"web"}, "nonce": {"n-1"}},
+			status:   302,
+			fragment: []string{"access_token", "id_token"},
+			omitted:  []string{"error"},
+		},
+		{
+			name:     "unsupported response type",
+			query:    url.Values{"response_type": {"code"}, "redirect_uri": {"https://app/cb"}, "sub": {"ann"}},
+			status:   302,
+			fragment: []string{"error"},
+			omitted:  []string{"access_token"},
+		},
+		{
+			name:     "login required",
+			query:    url.Values{"response_type": {"token"}, "redirect_uri": {"https://app/cb"}},
+			status:   302,
+			fragment: []string{"error"},
+			omitted:  []string{"access_token"},
+		},
+		{
+			name:   "relative redirect",
+			query:  url.Values{"response_type": {"token"}, "redirect_uri": {"/cb"}, "sub": {"ann"}},
+			status: 400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rsp, err := client.Get(p.Issuer + "/authorize?" + test.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+
+			if rsp.StatusCode != test.status {
+				t.Fatalf("Expected statuscode %d but received %d", test.status, rsp.StatusCode)
+			}
+			if test.status != 302 {
+				return
+			}
+
+			location, err := url.Parse(rsp.Header.Get("Location"))
+			if err != nil || !strings.HasPrefix(location.String(), "https://app/cb#") {
+				t.Fatalf("Expected a redirect to the redirect_uri but received %q", rsp.Header.Get("Location"))
+			}
+			fragment, _ := url.ParseQuery(location.Fragment)
+			for _, k := range test.fragment {
+				if fragment.Get(k) == "" {
+					t.Errorf("Expected %s within the fragment %v", k, fragment)
+				}
+			}
+			for _, k := range test.omitted {
+				if fragment.Get(k) != "" {
+					t.Errorf("Expected no %s within the fragment %v", k, fragment)
+				}
+			}
+
+			if id := fragment.Get("id_token"); id != "" {
+				_, claims := verifyJWT(t, p.tokens.JWK(), id)
+				if id == fragment.Get("access_token") || claims["aud"] != "web" || claims["nonce"] != "n-1" {
+					t.Errorf("Unexpected ID token claims %v", claims)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenIDProviderCredentials(t *testing.T) {
+	p, err := NewOpenIDProvider("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	req, _ := http.NewRequest("GET", "http://localhost/users", nil)
+	if err := p.Credentials(Claims{"sub": "42"}).Authorize(req); err != nil {
+		t.Fatal(err)
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if _, claims := verifyJWT(t, p.tokens.JWK(), token); claims["sub"] != "42" {
+		t.Errorf("Expected the subject of the credentials but received %v", claims)
+	}
+}
+
+// getJSON decodes the JSON document at the URL into v.
+func getJSON(t *testing.T, u string, v interface{}) {
+	t.Helper()
+
+	rsp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+package truth
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification written by
+// WriteOpenAPI.
+const OpenAPIVersion = "3.0.3"
+
+// openIDIssuer is the URL of the OpenID Connect issuer described by OpenAPI.
+var openIDIssuer string
+
+// SetOpenIDIssuer sets the URL of the OpenID Connect issuer, such as
+// https://login.example.com, whose discovery document OpenAPI refers to for
+// Definitions using AuthorizationOpenID.
+func SetOpenIDIssuer(issuer string) {
+	openIDIssuer = issuer
+}
+
+// OpenAPI returns an OpenAPI document describing every endpoint of the snapshot.
+// Route variables such as `:id` are written as `{id}`.
+func OpenAPI(s Snapshot, title string) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	schemes := map[string]interface{}{}
+
+	for _, e := range s.Endpoints {
+		path := openAPIPath(e.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+
+		op := map[string]interface{}{
+			"responses": map[string]interface{}{
+				"default": openAPIResponse(e),
+			},
+		}
+		if e.Name != "" {
+			op["summary"] = e.Name
+		}
+		if e.Description != "" {
+			op["description"] = dedent(e.Description)
+		}
+		if e.Package != "" {
+			op["tags"] = []string{e.Package}
+		}
+		if e.StatsKey != "" {
+			op["operationId"] = e.StatsKey
+		}
+
+		if params := openAPIParameters(e); len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if e.RequestBody != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					mimeOrJSON(e.MIMETypeRequest): map[string]interface{}{"schema": e.RequestBody},
+				},
+			}
+		}
+
+		if e.requiresAuth() {
+			name, scheme := openAPISecurityScheme(e.Authentication)
+			schemes[name] = scheme
+			op["security"] = []map[string][]string{{name: {}}}
+		}
+
+		paths[path][strings.ToLower(e.Method)] = op
+	}
+
+	doc := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]string{
+			"title":   title,
+			"version": s.APIVersion,
+		},
+		"paths": paths,
+	}
+
+	if len(schemes) > 0 {
+		doc["components"] = map[string]interface{}{"securitySchemes": schemes}
+	}
+
+	return doc
+}
+
+// WriteOpenAPI writes the OpenAPI document of the snapshot as indented JSON.
+func WriteOpenAPI(w io.Writer, s Snapshot, title string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(OpenAPI(s, title))
+}
+
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if isPathParam(s) {
+			segments[i] = "{" + pathParamName(s) + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func openAPIResponse(e Endpoint) map[string]interface{} {
+	rsp := map[string]interface{}{"description": "Response"}
+
+	if e.ResponseBody != nil {
+		rsp["content"] = map[string]interface{}{
+			mimeOrJSON(e.MIMETypeResponse): map[string]interface{}{"schema": e.ResponseBody},
+		}
+	}
+
+	if len(e.ResponseHeaders) > 0 {
+		headers := map[string]interface{}{}
+		for k, v := range e.ResponseHeaders {
+			headers[k] = map[string]interface{}{"schema": &Schema{Type: "string"}, "example": v}
+		}
+		rsp["headers"] = headers
+	}
+
+	return rsp
+}
+
+func openAPIParameters(e Endpoint) []map[string]interface{} {
+	var params []map[string]interface{}
+
+	for _, name := range e.Params {
+		schema := &Schema{Type: "string"}
+		if e.InputParams != nil && e.InputParams.Properties[name] != nil {
+			schema = e.InputParams.Properties[name]
+		}
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+
+	if e.QueryParams != nil {
+		names := make([]string, 0, len(e.QueryParams.Properties))
+		for name := range e.QueryParams.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "query",
+				"required": e.QueryParams.requires(name),
+				"schema":   e.QueryParams.Properties[name],
+			})
+		}
+	}
+
+	headers := make([]string, 0, len(e.RequestHeaders))
+	for name := range e.RequestHeaders {
+		headers = append(headers, name)
+	}
+	sort.Strings(headers)
+
+	for _, name := range headers {
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "header",
+			"required": true,
+			"schema":   &Schema{Type: "string"},
+			"example":  e.RequestHeaders[name],
+		})
+	}
+
+	return params
+}
+
+// openAPISecurityScheme describes the Authentication of a Definition. OpenID
+// Connect requires the absolute URL of the issuer's discovery document so,
+// until SetOpenIDIssuer is called, OpenID tokens are described as bearer JWTs.
+func openAPISecurityScheme(auth string) (string, map[string]interface{}) {
+	switch auth {
+	case AuthenticationChecksum:
+		return auth, map[string]interface{}{"type": "apiKey", "in": "header", "name": SignatureHeader}
+	case AuthorizationOpenID:
+		if openIDIssuer == "" {
+			return auth, map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+		}
+		return auth, map[string]interface{}{"type": "openIdConnect", "openIdConnectUrl": strings.TrimSuffix(openIDIssuer, "/") + "/.well-known/openid-configuration"}
+	}
+	return AuthorizationCredentials, map[string]interface{}{"type": "http", "scheme": "bearer"}
+}
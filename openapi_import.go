@@ -0,0 +1,989 @@
+package truth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	openAPIDocument struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths      map[string]*openAPIPathItem `json:"paths"`
+		Components openAPIComponents           `json:"components"`
+		Security   *[]map[string][]string      `json:"security"`
+	}
+
+	openAPIComponents struct {
+		Schemas         map[string]*openAPISchema         `json:"schemas"`
+		Parameters      map[string]*openAPIParameter      `json:"parameters"`
+		RequestBodies   map[string]*openAPIRequestBody    `json:"requestBodies"`
+		Responses       map[string]*openAPIResponseObject `json:"responses"`
+		SecuritySchemes map[string]*openAPISecurity       `json:"securitySchemes"`
+	}
+
+	openAPIPathItem struct {
+		Parameters []*openAPIParameter `json:"parameters"`
+		Get        *openAPIOperation   `json:"get"`
+		Put        *openAPIOperation   `json:"put"`
+		Post       *openAPIOperation   `json:"post"`
+		Delete     *openAPIOperation   `json:"delete"`
+		Options    *openAPIOperation   `json:"options"`
+		Head       *openAPIOperation   `json:"head"`
+		Patch      *openAPIOperation   `json:"patch"`
+		Trace      *openAPIOperation   `json:"trace"`
+	}
+
+	openAPIOperation struct {
+		OperationID string                            `json:"operationId"`
+		Summary     string                            `json:"summary"`
+		Description string                            `json:"description"`
+		Tags        []string                          `json:"tags"`
+		Parameters  []*openAPIParameter               `json:"parameters"`
+		RequestBody *openAPIRequestBody               `json:"requestBody"`
+		Responses   map[string]*openAPIResponseObject `json:"responses"`
+		Security    *[]map[string][]string            `json:"security"`
+	}
+
+	openAPIParameter struct {
+		Ref      string         `json:"$ref"`
+		Name     string         `json:"name"`
+		In       string         `json:"in"`
+		Required bool           `json:"required"`
+		Schema   *openAPISchema `json:"schema"`
+		Example  interface{}    `json:"example"`
+	}
+
+	openAPIRequestBody struct {
+		Ref     string                   `json:"$ref"`
+		Content map[string]*openAPIMedia `json:"content"`
+	}
+
+	openAPIResponseObject struct {
+		Ref     string                   `json:"$ref"`
+		Content map[string]*openAPIMedia `json:"content"`
+	}
+
+	openAPIMedia struct {
+		Schema   *openAPISchema             `json:"schema"`
+		Example  interface{}                `json:"example"`
+		Examples map[string]*openAPIExample `json:"examples"`
+	}
+
+	openAPIExample struct {
+		Value interface{} `json:"value"`
+	}
+
+	openAPISchema struct {
+		Ref                  string                    `json:"$ref"`
+		Type                 string                    `json:"type"`
+		Format               string                    `json:"format"`
+		Description          string                    `json:"description"`
+		Nullable             bool                      `json:"nullable"`
+		Properties           map[string]*openAPISchema `json:"properties"`
+		Required             []string                  `json:"required"`
+		Items                *openAPISchema            `json:"items"`
+		AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+		AllOf                []*openAPISchema          `json:"allOf"`
+		OneOf                []*openAPISchema          `json:"oneOf"`
+		AnyOf                []*openAPISchema          `json:"anyOf"`
+		Example              interface{}               `json:"example"`
+	}
+
+	openAPISecurity struct {
+		Type   string `json:"type"`
+		Scheme string `json:"scheme"`
+		In     string `json:"in"`
+		Name   string `json:"name"`
+	}
+
+	// openAPIImporter accumulates the Go source generated from a document.
+	openAPIImporter struct {
+		doc        *openAPIDocument
+		names      map[string]bool
+		components map[string]string
+		kinds      map[string]string // Named type to its underlying Go type.
+		imports    map[string]bool
+		declaring  map[string]bool // Structs whose fields are being declared.
+		types      bytes.Buffer
+		defs       bytes.Buffer
+	}
+)
+
+// ImportOpenAPI reads an OpenAPI 3 document, as JSON or YAML, and generates Go
+// source for the package holding a Definition and TestCases for every
+// operation. Request and response bodies, path parameters and query parameters
+// become Go structs. A test case is generated for every example response in the
+// document, expecting the response to hold the fields of the example, so
+// migrating an API to truth starts with tests already in place.
+//
+//	src, err := truth.ImportOpenAPI(spec, "users")
+//	...
+//	ioutil.WriteFile("definitions.go", src, 0644)
+func ImportOpenAPI(document []byte, pkg string) ([]byte, error) {
+	doc, err := parseOpenAPI(document)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &openAPIImporter{
+		doc:        doc,
+		names:      map[string]bool{},
+		components: map[string]string{},
+		kinds:      map[string]string{},
+		imports:    map[string]bool{"github.com/aarongreenlee/truth": true},
+		declaring:  map[string]bool{},
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := doc.Paths[p]
+		for _, method := range openAPIMethods {
+			if op := item.operation(method); op != nil {
+				im.operation(method, p, item, op)
+			}
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Definitions imported by `truth import` from the OpenAPI document of %s %s.\n\n", oneLine(doc.Info.Title), oneLine(doc.Info.Version))
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+
+	// The standard library is grouped before other packages.
+	var std, other []string
+	for i := range im.imports {
+		if strings.Contains(i, ".") {
+			other = append(other, strconv.Quote(i))
+		} else {
+			std = append(std, strconv.Quote(i))
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	fmt.Fprintf(&src, "import (\n%s\n\n%s\n)\n\n", strings.Join(std, "\n"), strings.Join(other, "\n"))
+
+	src.Write(im.types.Bytes())
+	src.Write(im.defs.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to format the generated source: %s", err)
+	}
+
+	return out, nil
+}
+
+// parseOpenAPI decodes the document. YAML is converted to JSON first so a single
+// set of struct tags describes the document.
+func parseOpenAPI(document []byte) (*openAPIDocument, error) {
+	if !json.Valid(document) {
+		var v interface{}
+		if err := yaml.Unmarshal(document, &v); err != nil {
+			return nil, fmt.Errorf("Unable to decode the OpenAPI document: %s", err)
+		}
+
+		var err error
+		if document, err = json.Marshal(normalizeYAML(v)); err != nil {
+			return nil, fmt.Errorf("Unable to decode the OpenAPI document: %s", err)
+		}
+	}
+
+	doc := &openAPIDocument{}
+	if err := json.Unmarshal(document, doc); err != nil {
+		return nil, fmt.Errorf("Unable to decode the OpenAPI document: %s", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("OpenAPI document has version %#v but 3.x is required", doc.OpenAPI)
+	}
+
+	return doc, nil
+}
+
+// normalizeYAML converts the maps decoded from YAML, which may have keys such as
+// the integer status codes of responses, into maps JSON can encode.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeYAML(e)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeYAML(e)
+		}
+	}
+	return v
+}
+
+var openAPIMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+}
+
+func (item *openAPIPathItem) operation(method string) *openAPIOperation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodHead:
+		return item.Head
+	case http.MethodPost:
+		return item.Post
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPatch:
+		return item.Patch
+	case http.MethodDelete:
+		return item.Delete
+	case http.MethodOptions:
+		return item.Options
+	case http.MethodTrace:
+		return item.Trace
+	}
+	return nil
+}
+
+// operation generates the Definition and test cases of an operation.
+func (im *openAPIImporter) operation(method, path string, item *openAPIPathItem, op *openAPIOperation) {
+	name := op.OperationID
+	if name == "" {
+		name = operationName(method, path)
+	}
+	name = goName(name)
+
+	params := im.parameters(item.Parameters, op.Parameters)
+	defName := im.unique(name + "Def")
+	casesName := im.unique(name + "Cases")
+
+	var fields []string
+	field := func(k, v string) {
+		fields = append(fields, fmt.Sprintf("%s: %s,", k, v))
+	}
+
+	field("Method", strconv.Quote(method))
+	field("Path", strconv.Quote(path))
+
+	var payload interface{}
+	if body := im.requestBody(op.RequestBody); body != nil {
+		if mime, media := pickMedia(body.Content); media != nil {
+			field("MIMETypeRequest", strconv.Quote(mime))
+			if t := im.goType(media.Schema, name+"Request"); t != "interface{}" {
+				field("RequestBody", fmt.Sprintf("truth.BodyDefinition{Data: %s}", im.zero(t)))
+			}
+			if examples := mediaExamples(media); len(examples) > 0 {
+				payload = examples[0].value
+			}
+		}
+	}
+
+	codes := responseCodes(op.Responses)
+	if code := successCode(codes); code != "" {
+		if rsp := im.response(op.Responses[code]); rsp != nil {
+			if mime, media := pickMedia(rsp.Content); media != nil {
+				field("MIMETypeResponse", strconv.Quote(mime))
+				if t := im.goType(media.Schema, name+"Response"); t != "interface{}" {
+					field("ResponseBody", fmt.Sprintf("truth.BodyDefinition{Data: %s}", im.zero(t)))
+				}
+			}
+		}
+	}
+
+	// Only the headers every request must send are required of test cases.
+	var pairs []string
+	for _, p := range params.in("header") {
+		if p.Required {
+			pairs = append(pairs, fmt.Sprintf("%q: %q,", p.Name, fmt.Sprint(parameterExample(p))))
+		}
+	}
+	if len(pairs) > 0 {
+		field("RequestHeaders", fmt.Sprintf("map[string]string{\n%s\n}", strings.Join(pairs, "\n")))
+	}
+
+	if p := params.in("path"); len(p) > 0 {
+		field("InputParams", im.parameterStruct(name+"Params", p)+"{}")
+	}
+	if q := params.in("query"); len(q) > 0 {
+		field("QueryParams", im.parameterStruct(name+"Query", q)+"{}")
+	}
+
+	if auth := im.authentication(op); auth != "" {
+		field("Authentication", auth)
+	}
+
+	if op.Summary != "" {
+		field("Name", strconv.Quote(op.Summary))
+	}
+	if op.Description != "" {
+		field("Description", goString(strings.TrimSpace(op.Description)))
+	}
+	if len(op.Tags) > 0 {
+		field("Package", strconv.Quote(op.Tags[0]))
+	}
+	if op.OperationID != "" {
+		field("StatsKey", strconv.Quote(op.OperationID))
+	}
+
+	fmt.Fprintf(&im.defs, "// %s is `%s %s`.\n", defName, method, path)
+	fmt.Fprintf(&im.defs, "var %s = truth.Definition{\n%s\n}\n\n", defName, strings.Join(fields, "\n"))
+
+	cases := im.cases(name, path, params, payload, op.Responses, codes)
+	fmt.Fprintf(&im.defs, "// %s are generated from the examples of `%s %s`.\n", casesName, method, path)
+	fmt.Fprintf(&im.defs, "var %s = truth.TestCases{\n%s}\n\n", casesName, strings.Join(cases, ""))
+}
+
+// cases returns a test case for every example response. Without examples a
+// single case expects the successful status code.
+func (im *openAPIImporter) cases(name, path string, params openAPIParameterList, payload interface{}, responses map[string]*openAPIResponseObject, codes []string) []string {
+	tcPath := fillPath(path, func(n string) string {
+		for _, p := range params.in("path") {
+			if p.Name == n {
+				return fmt.Sprint(parameterExample(p))
+			}
+		}
+		return "1"
+	})
+
+	query := url.Values{}
+	for _, p := range params.in("query") {
+		if p.Required {
+			query.Set(p.Name, fmt.Sprint(parameterExample(p)))
+		}
+	}
+	if len(query) > 0 {
+		tcPath += "?" + query.Encode()
+	}
+
+	testCase := func(label string, status int, body interface{}) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "{\nName: %q,\n", label)
+		if tcPath != path {
+			fmt.Fprintf(&b, "Path: %q,\n", tcPath)
+		}
+		if payload != nil {
+			fmt.Fprintf(&b, "Payload: %s,\n", goBytes(payload))
+		}
+		fmt.Fprintf(&b, "Status: %d,\n", status)
+		if terms := exampleTerms(body); len(terms) > 0 {
+			fmt.Fprintf(&b, "Contains: []string{%s},\n", strings.Join(terms, ", "))
+		}
+		b.WriteString("},\n")
+		return b.String()
+	}
+
+	var cases []string
+	for _, code := range codes {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			continue
+		}
+
+		rsp := im.response(responses[code])
+		if rsp == nil {
+			continue
+		}
+
+		_, media := pickMedia(rsp.Content)
+		if media == nil {
+			continue
+		}
+
+		for _, ex := range mediaExamples(media) {
+			if ex.value == nil {
+				continue
+			}
+			label := fmt.Sprintf("%s returns %d", name, status)
+			if ex.name != "" {
+				label += " (" + ex.name + ")"
+			}
+			cases = append(cases, testCase(label, status, ex.value))
+		}
+	}
+
+	if len(cases) == 0 {
+		status := http.StatusOK
+		if n, err := strconv.Atoi(successCode(codes)); err == nil {
+			status = n
+		}
+		cases = append(cases, testCase(fmt.Sprintf("%s returns %d", name, status), status, nil))
+	}
+
+	return cases
+}
+
+type openAPIParameterList []*openAPIParameter
+
+func (params openAPIParameterList) in(location string) openAPIParameterList {
+	var out openAPIParameterList
+	for _, p := range params {
+		if p.In == location {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parameters resolves the parameters of the path and operation. Operation
+// parameters override path parameters of the same name and location.
+func (im *openAPIImporter) parameters(lists ...[]*openAPIParameter) openAPIParameterList {
+	var out openAPIParameterList
+	index := map[string]int{}
+
+	for _, list := range lists {
+		for _, p := range list {
+			if p.Ref != "" {
+				if p = im.doc.Components.Parameters[refName(p.Ref)]; p == nil {
+					continue
+				}
+			}
+
+			key := p.In + ":" + p.Name
+			if i, ok := index[key]; ok {
+				out[i] = p
+				continue
+			}
+			index[key] = len(out)
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+func (im *openAPIImporter) parameterStruct(name string, params openAPIParameterList) string {
+	s := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	for _, p := range params {
+		schema := p.Schema
+		if schema == nil {
+			schema = &openAPISchema{Type: "string"}
+		}
+		s.Properties[p.Name] = schema
+		if p.Required || p.In == "path" {
+			s.Required = append(s.Required, p.Name)
+		}
+	}
+	return im.goType(s, name)
+}
+
+func (im *openAPIImporter) requestBody(b *openAPIRequestBody) *openAPIRequestBody {
+	if b != nil && b.Ref != "" {
+		return im.doc.Components.RequestBodies[refName(b.Ref)]
+	}
+	return b
+}
+
+func (im *openAPIImporter) response(r *openAPIResponseObject) *openAPIResponseObject {
+	if r != nil && r.Ref != "" {
+		return im.doc.Components.Responses[refName(r.Ref)]
+	}
+	return r
+}
+
+// authentication maps the security requirements of the operation onto the
+// Authentication of a Definition.
+func (im *openAPIImporter) authentication(op *openAPIOperation) string {
+	security := op.Security
+	if security == nil {
+		security = im.doc.Security
+	}
+	if security == nil {
+		return ""
+	}
+
+	var names []string
+	for _, requirement := range *security {
+		// An empty requirement makes credentials optional.
+		if len(requirement) == 0 {
+			return "truth.AuthorizationNone"
+		}
+		for n := range requirement {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		return "truth.AuthorizationNone"
+	}
+	sort.Strings(names)
+
+	scheme := im.doc.Components.SecuritySchemes[names[0]]
+	switch {
+	case scheme == nil:
+		return "truth.AuthorizationCredentials"
+	case scheme.Type == "openIdConnect":
+		return "truth.AuthorizationOpenID"
+	case scheme.Type == "apiKey" && http.CanonicalHeaderKey(scheme.Name) == SignatureHeader:
+		return "truth.AuthenticationChecksum"
+	}
+	return "truth.AuthorizationCredentials"
+}
+
+// goType returns the Go type of the schema, declaring structs as required. The
+// hint names types declared for inline schemas.
+func (im *openAPIImporter) goType(s *openAPISchema, hint string) string {
+	if s == nil {
+		return "interface{}"
+	}
+
+	if s.Ref != "" {
+		return im.component(refName(s.Ref))
+	}
+
+	if len(s.AllOf) > 0 {
+		return im.goType(im.merge(s), hint)
+	}
+
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return "interface{}"
+	}
+
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			im.imports["time"] = true
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		switch s.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + im.goType(s.Items, hint+"Item")
+	}
+
+	if len(s.Properties) > 0 {
+		return im.declareStruct(im.unique(hint), s)
+	}
+
+	if ap := s.additionalProperties(); ap != nil {
+		return "map[string]" + im.goType(ap, hint+"Value")
+	}
+
+	if s.Type == "object" {
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+// component returns the Go type of a component schema, declaring it once.
+func (im *openAPIImporter) component(name string) string {
+	if t, ok := im.components[name]; ok {
+		return t
+	}
+
+	s := im.doc.Components.Schemas[name]
+	typeName := im.unique(goName(name))
+	im.components[name] = typeName
+
+	if s != nil && len(s.AllOf) > 0 {
+		s = im.merge(s)
+	}
+
+	if s != nil && s.Ref == "" && len(s.Properties) > 0 {
+		im.declareStruct(typeName, s)
+		return typeName
+	}
+
+	underlying := im.goType(s, typeName+"Value")
+	im.kinds[typeName] = underlying
+	if s != nil && s.Description != "" {
+		fmt.Fprintf(&im.types, "%s", goComment(s.Description))
+	}
+	fmt.Fprintf(&im.types, "type %s %s\n\n", typeName, underlying)
+
+	return typeName
+}
+
+// declareStruct declares the object schema as a struct. Optional fields are
+// tagged `omitempty` and nullable fields are pointers so SchemaOf reflects the
+// schema back. Fields referring to a struct which is still being declared are
+// pointers, even when required, so recursive types compile.
+func (im *openAPIImporter) declareStruct(name string, s *openAPISchema) string {
+	im.kinds[name] = "struct"
+	im.declaring[name] = true
+	defer delete(im.declaring, name)
+
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	fields := map[string]bool{}
+
+	var b strings.Builder
+	for _, p := range props {
+		ps := s.Properties[p]
+		field := unique(fields, goName(p))
+		t := im.goType(ps, name+field)
+
+		tag := p
+		if !required[p] {
+			tag += ",omitempty"
+		}
+
+		// Optional structs are pointers, as are structs being declared which
+		// would otherwise contain themselves.
+		if (ps != nil && ps.Nullable || im.kinds[t] == "struct" && (!required[p] || im.declaring[t])) && !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "interface{}" {
+			t = "*" + t
+		}
+
+		if ps != nil && ps.Description != "" {
+			b.WriteString(goComment(ps.Description))
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q`\n", field, t, tag)
+	}
+
+	if s.Description != "" {
+		im.types.WriteString(goComment(s.Description))
+	}
+	fmt.Fprintf(&im.types, "type %s struct {\n%s}\n\n", name, b.String())
+
+	return name
+}
+
+// merge combines the schemas of allOf into a single object schema.
+func (im *openAPIImporter) merge(s *openAPISchema) *openAPISchema {
+	out := &openAPISchema{Type: "object", Description: s.Description, Properties: map[string]*openAPISchema{}}
+
+	parts := append([]*openAPISchema{s}, s.AllOf...)
+	for _, p := range parts {
+		if p.Ref != "" {
+			p = im.doc.Components.Schemas[refName(p.Ref)]
+		}
+		if p == nil {
+			continue
+		}
+		if len(p.AllOf) > 0 && p != s {
+			p = im.merge(p)
+		}
+		for k, v := range p.Properties {
+			out.Properties[k] = v
+		}
+		out.Required = append(out.Required, p.Required...)
+	}
+
+	return out
+}
+
+func (s *openAPISchema) additionalProperties() *openAPISchema {
+	if len(s.AdditionalProperties) == 0 || s.AdditionalProperties[0] != '{' {
+		return nil
+	}
+
+	ap := &openAPISchema{}
+	if json.Unmarshal(s.AdditionalProperties, ap) != nil {
+		return nil
+	}
+	return ap
+}
+
+// zero returns a Go expression holding the zero value of the type.
+func (im *openAPIImporter) zero(t string) string {
+	switch {
+	case strings.HasPrefix(t, "*"):
+		return "&" + t[1:] + "{}"
+	case strings.HasPrefix(t, "[]"), strings.HasPrefix(t, "map["), t == "time.Time", im.kinds[t] == "struct":
+		return t + "{}"
+	case im.kinds[t] != "":
+		return t + "(" + im.zero(im.kinds[t]) + ")"
+	case t == "string":
+		return `""`
+	case t == "bool":
+		return "false"
+	case t == "interface{}":
+		return "nil"
+	}
+	return t + "(0)"
+}
+
+// unique returns the name of a type or variable, suffixed with a number when it
+// is already in use.
+func (im *openAPIImporter) unique(name string) string {
+	return unique(im.names, name)
+}
+
+// unique returns the name, suffixed with a number when it is already among the
+// names, and adds it to the names.
+func unique(names map[string]bool, name string) string {
+	n := name
+	for i := 2; names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	names[n] = true
+	return n
+}
+
+type namedExample struct {
+	name  string
+	value interface{}
+}
+
+// mediaExamples returns the examples of the media in a stable order. A media
+// without examples returns the example of its schema, which may be nil.
+func mediaExamples(m *openAPIMedia) []namedExample {
+	if m.Example != nil {
+		return []namedExample{{value: m.Example}}
+	}
+
+	if len(m.Examples) > 0 {
+		names := make([]string, 0, len(m.Examples))
+		for n := range m.Examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		examples := make([]namedExample, 0, len(names))
+		for _, n := range names {
+			if e := m.Examples[n]; e != nil {
+				examples = append(examples, namedExample{name: n, value: e.Value})
+			}
+		}
+		return examples
+	}
+
+	var v interface{}
+	if m.Schema != nil {
+		v = m.Schema.Example
+	}
+	return []namedExample{{value: v}}
+}
+
+// exampleTerms returns Go literals of the fieldTerms of an example. A response
+// is expected to contain the names rather than to equal the example because
+// values such as identifiers and timestamps differ between servers.
+func exampleTerms(example interface{}) []string {
+	terms := fieldTerms(example)
+	for i, t := range terms {
+		terms[i] = goString(t)
+	}
+	return terms
+}
+
+// fieldTerms returns the quoted property names of a decoded JSON object, or of
+// the first object of an array, in order.
+func fieldTerms(v interface{}) []string {
+	if items, ok := v.([]interface{}); ok && len(items) > 0 {
+		v = items[0]
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	terms := make([]string, 0, len(obj))
+	for k := range obj {
+		terms = append(terms, strconv.Quote(k))
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// pickMedia prefers JSON among the content types of a body.
+func pickMedia(content map[string]*openAPIMedia) (string, *openAPIMedia) {
+	if m, ok := content["application/json"]; ok {
+		return "application/json", m
+	}
+
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		if strings.HasSuffix(t, "json") {
+			return t, content[t]
+		}
+	}
+	if len(types) > 0 {
+		return types[0], content[types[0]]
+	}
+	return "", nil
+}
+
+func parameterExample(p *openAPIParameter) interface{} {
+	if p.Example != nil {
+		return p.Example
+	}
+	if p.Schema != nil && p.Schema.Example != nil {
+		return p.Schema.Example
+	}
+	if p.Schema != nil && (p.Schema.Type == "integer" || p.Schema.Type == "number") {
+		return 1
+	}
+	return p.Name
+}
+
+// responseCodes returns the response codes in order.
+func responseCodes(responses map[string]*openAPIResponseObject) []string {
+	codes := make([]string, 0, len(responses))
+	for c := range responses {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// successCode returns the first 2XX response code, or the default response.
+func successCode(codes []string) string {
+	for _, c := range codes {
+		if strings.HasPrefix(c, "2") {
+			return c
+		}
+	}
+	for _, c := range codes {
+		if c == "default" {
+			return c
+		}
+	}
+	return ""
+}
+
+// operationName names an operation without an operationId from its method and
+// path, such as GetUsersByID for `GET /users/{id}`.
+func operationName(method, path string) string {
+	name := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if isPathParam(segment) {
+			name += " by " + pathParamName(segment)
+			continue
+		}
+		name += " " + segment
+	}
+	return name
+}
+
+var goInitialisms = map[string]string{
+	"api": "API", "http": "HTTP", "id": "ID", "json": "JSON",
+	"uri": "URI", "url": "URL", "uuid": "UUID",
+}
+
+// oneLine collapses the whitespace of text, including newlines, so it can be
+// written into a line comment.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// goName converts the name into an exported Go identifier.
+func goName(s string) string {
+	var words []string
+	word := []rune{}
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if i, ok := goInitialisms[strings.ToLower(w)]; ok {
+			b.WriteString(i)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// refName returns the name of a component from a reference such as
+// `#/components/schemas/User`.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// goBytes returns a Go expression holding the value as a JSON document.
+func goBytes(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b = []byte(fmt.Sprint(v))
+	}
+	return "[]byte(" + goString(string(b)) + ")"
+}
+
+// goString returns a Go string literal, preferring a raw literal for text with
+// quotes or line breaks.
+func goString(s string) string {
+	if strings.ContainsAny(s, "\"\n") && !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func goComment(s string) string {
+	var b strings.Builder
+	for _, l := range strings.Split(dedent(s), "\n") {
+		b.WriteString(strings.TrimSpace("// "+l) + "\n")
+	}
+	return b.String()
+}
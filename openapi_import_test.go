@@ -0,0 +1,157 @@
+package truth
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const importSpec = `
+openapi: 3.0.0
+info: {title: "Users\n// API", version: "1.0\n"}
+security: [{bearer: []}]
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}, example: 7}
+    get:
+      operationId: getUser
+      summary: Get User
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+              example: {id: 7, name: Ann}
+        "404":
+          description: Missing
+  /status:
+    get:
+      security: []
+      parameters:
+        - {name: X-Request-Id, in: header, required: true, schema: {type: string}, example: abc}
+        - {name: X-Trace, in: header, schema: {type: string}}
+      responses:
+        "204": {description: No Content}
+components:
+  securitySchemes:
+    bearer: {type: http, scheme: bearer}
+  schemas:
+    User:
+      type: object
+      required: [id, name, manager]
+      properties:
+        id: {type: integer, format: int64}
+        name: {type: string}
+        user_id: {type: string}
+        userId: {type: string}
+        created: {type: string, format: date-time}
+        manager: {$ref: "#/components/schemas/User"}
+        team: {$ref: "#/components/schemas/Team"}
+    Team:
+      type: object
+      required: [lead]
+      properties:
+        lead: {$ref: "#/components/schemas/User"}
+`
+
+func TestImportOpenAPI(t *testing.T) {
+	src, err := ImportOpenAPI([]byte(importSpec), "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "users.go", src, 0); err != nil {
+		t.Fatalf("Expected valid Go source but received %s:\n%s", err, src)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "package", expected: "package users"},
+		{name: "one line comment", expected: "// Definitions imported by `truth import` from the OpenAPI document of Users // API 1.0.\n"},
+		{name: "required headers", expected: `RequestHeaders: map[string]string{
+		"X-Request-Id": "abc",
+	},`},
+		{name: "time import", expected: `"time"`},
+		{name: "int64", expected: "ID      int64     `json:\"id\"`"},
+		{name: "optional time", expected: "Created time.Time `json:\"created,omitempty\"`"},
+		{name: "required self reference", expected: "Manager *User     `json:\"manager\"`"},
+		{name: "required mutual reference", expected: "Lead *User `json:\"lead\"`"},
+		{name: "optional struct", expected: "Team    *Team     `json:\"team,omitempty\"`"},
+		{name: "unique field names", expected: "UserID  string    `json:\"userId,omitempty\"`\n\tUserID2 string    `json:\"user_id,omitempty\"`"},
+		{name: "path parameters", expected: "InputParams:      GetUserParams{}"},
+		{name: "authentication", expected: "Authentication:   truth.AuthorizationCredentials"},
+		{name: "optional authentication", expected: "Authentication: truth.AuthorizationNone"},
+		{name: "example path", expected: `Path:     "/users/7"`},
+		{name: "example fields", expected: "Contains: []string{`\"id\"`, `\"name\"`}"},
+		{name: "case without examples", expected: "Status: 204"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !strings.Contains(string(src), test.expected) {
+				t.Errorf("Expected the source to contain:\n%s\nReceived:\n%s", test.expected, src)
+			}
+		})
+	}
+
+	if strings.Contains(string(src), "X-Trace") {
+		t.Errorf("Expected no optional headers but received:\n%s", src)
+	}
+	if strings.Contains(string(src), "ExpectBody") {
+		t.Errorf("Expected no exact bodies built from examples but received:\n%s", src)
+	}
+}
+
+func TestImportOpenAPIRejectsSwagger(t *testing.T) {
+	if _, err := ImportOpenAPI([]byte(`{"swagger": "2.0"}`), "users"); err == nil {
+		t.Error("Expected an error for a Swagger 2 document")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "user_id", expected: "UserID"},
+		{name: "userId", expected: "UserID"},
+		{name: "get users by id", expected: "GetUsersByID"},
+		{name: "api-key", expected: "APIKey"},
+		{name: "2fa", expected: "N2fa"},
+		{name: "$", expected: "Field"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := goName(test.name); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExampleTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		example  interface{}
+		expected string
+	}{
+		{name: "nil"},
+		{name: "scalar", example: "ok"},
+		{name: "object", example: map[string]interface{}{"name": "Ann", "id": 1}, expected: "`\"id\"` `\"name\"`"},
+		{name: "array", example: []interface{}{map[string]interface{}{"id": 1}}, expected: "`\"id\"`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := strings.Join(exampleTerms(test.example), " "); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
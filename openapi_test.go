@@ -0,0 +1,57 @@
+package truth
+
+import (
+	"testing"
+)
+
+func TestOpenAPIParametersSorted(t *testing.T) {
+	e := Endpoint{
+		Path:   "/users/{id}",
+		Params: []string{"id"},
+		QueryParams: &Schema{Type: "object", Properties: map[string]*Schema{
+			"sort": {Type: "string"}, "limit": {Type: "integer"}, "offset": {Type: "integer"},
+		}},
+		RequestHeaders: map[string]string{"X-Tenant": "a", "Accept-Language": "en", "X-Request-Id": "1"},
+	}
+
+	expected := []string{"id", "limit", "offset", "sort", "Accept-Language", "X-Request-Id", "X-Tenant"}
+
+	// Maps are ranged in random order so repeat to catch an unsorted result.
+	for i := 0; i < 10; i++ {
+		params := openAPIParameters(e)
+		if len(params) != len(expected) {
+			t.Fatalf("Expected %d parameters but received %d", len(expected), len(params))
+		}
+		for j, p := range params {
+			if p["name"] != expected[j] {
+				t.Fatalf("Expected parameter %d to be %s but received %s", j, expected[j], p["name"])
+			}
+		}
+	}
+}
+
+func TestOpenAPISecuritySchemeOpenID(t *testing.T) {
+	defer SetOpenIDIssuer(openIDIssuer)
+
+	tests := []struct {
+		name     string
+		issuer   string
+		expected string
+	}{
+		{name: "without issuer", expected: `{"bearerFormat":"JWT","scheme":"bearer","type":"http"}`},
+		{
+			name:     "issuer",
+			issuer:   "https://login.example.com/",
+			expected: `{"openIdConnectUrl":"https://login.example.com/.well-known/openid-configuration","type":"openIdConnect"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetOpenIDIssuer(test.issuer)
+			if _, scheme := openAPISecurityScheme(AuthorizationOpenID); string(JSON(scheme)) != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, JSON(scheme))
+			}
+		})
+	}
+}
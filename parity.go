@@ -0,0 +1,126 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// Difference describes how two responses to the same test case differ.
+	Difference struct {
+		// Field is `status`, `header <Name>` or `body`.
+		Field string
+		A, B  string
+	}
+)
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s differs:\nA: `%s`\nB: `%s`", d.Field, d.A, d.B)
+}
+
+// transportHeaders are written by the HTTP server and client rather than the
+// handler so they are never compared.
+var transportHeaders = []string{"Connection", "Content-Length", "Date", "Keep-Alive", "Transfer-Encoding"}
+
+var (
+	parityMu      sync.Mutex
+	parityIgnored []string
+)
+
+// IgnoreParityHeaders excludes headers, such as those added by a proxy in front
+// of a remote host, from the comparison made by ModeParity.
+func IgnoreParityHeaders(names ...string) {
+	parityMu.Lock()
+	defer parityMu.Unlock()
+
+	parityIgnored = append(parityIgnored, names...)
+}
+
+func parityIgnore() map[string]bool {
+	parityMu.Lock()
+	defer parityMu.Unlock()
+
+	ignore := map[string]bool{}
+	for _, h := range append(transportHeaders, parityIgnored...) {
+		ignore[http.CanonicalHeaderKey(h)] = true
+	}
+	return ignore
+}
+
+// comparison selects what compareResponses compares.
+type comparison struct {
+	// headers lists the headers compared. Every header which is not ignored is
+	// compared when none are listed.
+	headers []string
+	// ignore holds the canonical names of headers which are never compared.
+	ignore map[string]bool
+	// fields lists the volatile fields of JSON bodies which are not compared.
+	// See DiffRunner.Ignore.
+	fields []string
+}
+
+// compareResponses returns the differences between the responses. Headers are
+// compared by their canonical names and JSON bodies field by field, so bodies
+// holding the same JSON value are equal regardless of formatting and key order.
+// ModeParity and the DiffRunner share it.
+func compareResponses(a *httptest.ResponseRecorder, aBody []byte, b *httptest.ResponseRecorder, bBody []byte, c comparison) []Difference {
+	var diffs []Difference
+
+	if a.Code != b.Code {
+		diffs = append(diffs, Difference{Field: "status", A: fmt.Sprint(a.Code), B: fmt.Sprint(b.Code)})
+	}
+
+	for _, k := range c.headerNames(a.Header(), b.Header()) {
+		av, bv := strings.Join(a.Header().Values(k), ", "), strings.Join(b.Header().Values(k), ", ")
+		if av != bv {
+			diffs = append(diffs, Difference{Field: "header " + k, A: av, B: bv})
+		}
+	}
+
+	var av, bv interface{}
+	if json.Unmarshal(aBody, &av) != nil || json.Unmarshal(bBody, &bv) != nil {
+		if a, b := strings.TrimSpace(string(aBody)), strings.TrimSpace(string(bBody)); a != b {
+			diffs = append(diffs, Difference{Field: "body", A: a, B: b})
+		}
+		return diffs
+	}
+
+	for _, path := range c.fields {
+		av = removeJSON(av, strings.Split(path, "."))
+		bv = removeJSON(bv, strings.Split(path, "."))
+	}
+
+	return append(diffs, diffJSON("body", av, bv)...)
+}
+
+// headerNames returns the sorted canonical names of the headers compared.
+func (c comparison) headerNames(a, b http.Header) []string {
+	names := map[string]bool{}
+	if len(c.headers) > 0 {
+		for _, k := range c.headers {
+			names[http.CanonicalHeaderKey(k)] = true
+		}
+	} else {
+		for k := range a {
+			names[http.CanonicalHeaderKey(k)] = true
+		}
+		for k := range b {
+			names[http.CanonicalHeaderKey(k)] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		if !c.ignore[k] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
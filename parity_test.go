@@ -0,0 +1,97 @@
+package truth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareResponses(t *testing.T) {
+	response := func(code int, header http.Header, body string) (*httptest.ResponseRecorder, []byte) {
+		RR := httptest.NewRecorder()
+		for k, v := range header {
+			RR.Header()[k] = v
+		}
+		RR.WriteHeader(code)
+		return RR, []byte(body)
+	}
+
+	defer func(ignored []string) { parityIgnored = ignored }(parityIgnored)
+	parityIgnored = nil
+	IgnoreParityHeaders("x-served-by")
+
+	json := http.Header{"Content-Type": {MIMETypeJSON}}
+
+	tests := []struct {
+		name       string
+		aCode      int
+		aHeader    http.Header
+		aBody      string
+		bCode      int
+		bHeader    http.Header
+		bBody      string
+		comparison comparison
+		expected   []Difference
+	}{
+		{
+			name:  "equal",
+			aCode: 200, aHeader: json, aBody: `{"id":1,"name":"Ann"}`,
+			bCode: 200, bHeader: json, bBody: "{\"name\": \"Ann\", \"id\": 1}\n",
+		},
+		{
+			name:  "status",
+			aCode: 200, aHeader: json, aBody: `{"id":1}`,
+			bCode: 201, bHeader: json, bBody: `{"id":1}`,
+			expected: []Difference{{Field: "status", A: "200", B: "201"}},
+		},
+		{
+			name:  "body",
+			aCode: 200, aHeader: json, aBody: `{"id":1,"name":"Ann"}`,
+			bCode: 200, bHeader: json, bBody: `{"id":1,"name":"Bob"}`,
+			expected: []Difference{{Field: "body.name", A: `"Ann"`, B: `"Bob"`}},
+		},
+		{
+			name:  "text body",
+			aCode: 200, aBody: "ok\n",
+			bCode: 200, bBody: "okay",
+			expected: []Difference{{Field: "body", A: "ok", B: "okay"}},
+		},
+		{
+			name:  "header",
+			aCode: 200, aHeader: http.Header{"Content-Type": {MIMETypeJSON}, "Cache-Control": {"no-store"}}, aBody: `{}`,
+			bCode: 200, bHeader: json, bBody: `{}`,
+			expected: []Difference{{Field: "header Cache-Control", A: "no-store", B: ""}},
+		},
+		{
+			name:  "ignored headers",
+			aCode: 200, aHeader: http.Header{"Date": {"Mon, 01 Jan 2018 00:00:00 GMT"}, "Content-Length": {"2"}, "X-Served-By": {"a"}}, aBody: `{}`,
+			bCode: 200, bHeader: http.Header{"Date": {"Tue, 02 Jan 2018 00:00:00 GMT"}, "X-Served-By": {"b"}}, bBody: `{}`,
+			comparison: comparison{ignore: parityIgnore()},
+		},
+		{
+			name:  "listed headers",
+			aCode: 200, aHeader: http.Header{"Content-Type": {MIMETypeJSON}, "Etag": {"a"}}, aBody: `{}`,
+			bCode: 200, bHeader: http.Header{"Content-Type": {"text/plain"}, "Etag": {"b"}}, bBody: `{}`,
+			comparison: comparison{headers: []string{"content-type"}},
+			expected:   []Difference{{Field: "header Content-Type", A: MIMETypeJSON, B: "text/plain"}},
+		},
+		{
+			name:  "ignored fields",
+			aCode: 200, aHeader: json, aBody: `{"id":1,"etag":"a"}`,
+			bCode: 200, bHeader: json, bBody: `{"id":1,"etag":"b"}`,
+			comparison: comparison{fields: []string{"etag"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, aBody := response(test.aCode, test.aHeader, test.aBody)
+			b, bBody := response(test.bCode, test.bHeader, test.bBody)
+
+			actual := compareResponses(a, aBody, b, bBody, test.comparison)
+			if string(JSON(actual)) != string(JSON(test.expected)) {
+				t.Errorf("Expected %s but received %s", JSON(test.expected), JSON(actual))
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package truth
+
+import (
+	"net/url"
+	"strings"
+)
+
+// isPathParam reports if a path segment is a route variable such as `:id`,
+// `{id}` or the catch-all `*path`.
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, ":") ||
+		strings.HasPrefix(segment, "*") ||
+		(strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"))
+}
+
+// pathParamName returns the name of a route variable segment.
+func pathParamName(segment string) string {
+	return strings.Trim(segment, ":*{}")
+}
+
+// pathParams returns the names of the route variables within a path template
+// in the order they appear.
+func pathParams(template string) []string {
+	var names []string
+
+	for _, segment := range strings.Split(template, "/") {
+		if isPathParam(segment) {
+			names = append(names, pathParamName(segment))
+		}
+	}
+
+	return names
+}
+
+// fillPath replaces every route variable in the path template with the value
+// returned by fn. Values are escaped so the result is always a valid path.
+func fillPath(template string, fn func(name string) string) string {
+	segments := strings.Split(template, "/")
+
+	for i, segment := range segments {
+		if isPathParam(segment) {
+			segments[i] = url.PathEscape(fn(pathParamName(segment)))
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// matchPath reports if the path matches the template. When it does the values
+// of the route variables are returned by name. A query string on the path is
+// ignored.
+func matchPath(template, path string) (map[string]string, bool) {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	want := strings.Split(strings.TrimSuffix(template, "/"), "/")
+	have := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	params := map[string]string{}
+
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "*") && i < len(have) {
+			params[pathParamName(segment)] = strings.Join(have[i:], "/")
+			return params, true
+		}
+
+		if i >= len(have) {
+			return nil, false
+		}
+
+		if isPathParam(segment) {
+			value, err := url.PathUnescape(have[i])
+			if err != nil || value == "" {
+				return nil, false
+			}
+			params[pathParamName(segment)] = value
+			continue
+		}
+
+		if segment != have[i] {
+			return nil, false
+		}
+	}
+
+	if len(want) != len(have) {
+		return nil, false
+	}
+
+	return params, true
+}
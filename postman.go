@@ -0,0 +1,744 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PostmanSchema is the schema of the Postman collections written by
+// ExportPostman.
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type (
+	// PostmanCollection is a Postman v2.1 collection. A collection built by
+	// NewPostmanCollection holds a folder for every Package and within it a
+	// folder for every Definition holding a request for each test case.
+	// Requests are sent to the `baseUrl` variable and credentials are held by
+	// variables such as `token` for the user to fill in.
+	PostmanCollection struct {
+		Info     PostmanInfo       `json:"info"`
+		Item     []PostmanItem     `json:"item"`
+		Auth     *PostmanAuth      `json:"auth,omitempty"`
+		Event    []PostmanEvent    `json:"event,omitempty"`
+		Variable []PostmanVariable `json:"variable,omitempty"`
+	}
+
+	// PostmanInfo names the collection.
+	PostmanInfo struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Schema      string `json:"schema"`
+	}
+
+	// PostmanItem is a folder when it holds items and otherwise a request.
+	PostmanItem struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Item        []PostmanItem   `json:"item,omitempty"`
+		Request     *PostmanRequest `json:"request,omitempty"`
+		Auth        *PostmanAuth    `json:"auth,omitempty"`
+		Event       []PostmanEvent  `json:"event,omitempty"`
+	}
+
+	// PostmanRequest is the request of an item.
+	PostmanRequest struct {
+		Method string            `json:"method"`
+		Header []PostmanVariable `json:"header"`
+		URL    PostmanURL        `json:"url"`
+		Body   *PostmanBody      `json:"body,omitempty"`
+		Auth   *PostmanAuth      `json:"auth,omitempty"`
+	}
+
+	// PostmanURL is the URL of a request. Path variables such as `:id` are valued
+	// by Variable. A URL written as a string is read into Raw.
+	PostmanURL struct {
+		Raw      string            `json:"raw"`
+		Host     []string          `json:"host,omitempty"`
+		Path     []string          `json:"path,omitempty"`
+		Query    []PostmanVariable `json:"query,omitempty"`
+		Variable []PostmanVariable `json:"variable,omitempty"`
+	}
+
+	// PostmanBody is the body of a request. Raw and URL encoded bodies are
+	// supported.
+	PostmanBody struct {
+		Mode       string                 `json:"mode"`
+		Raw        string                 `json:"raw,omitempty"`
+		URLEncoded []PostmanVariable      `json:"urlencoded,omitempty"`
+		Options    map[string]interface{} `json:"options,omitempty"`
+	}
+
+	// PostmanAuth authorizes the requests of a collection, folder or request. The
+	// parameters of the Type are held by the field of the same name.
+	PostmanAuth struct {
+		Type   string            `json:"type"`
+		Bearer []PostmanVariable `json:"bearer,omitempty"`
+		Basic  []PostmanVariable `json:"basic,omitempty"`
+		APIKey []PostmanVariable `json:"apikey,omitempty"`
+		OAuth2 []PostmanVariable `json:"oauth2,omitempty"`
+	}
+
+	// PostmanEvent runs a script before a request, when Listen is `prerequest`,
+	// or after it, when Listen is `test`.
+	PostmanEvent struct {
+		Listen string        `json:"listen"`
+		Script PostmanScript `json:"script"`
+	}
+
+	// PostmanScript is JavaScript run by Postman.
+	PostmanScript struct {
+		Type string   `json:"type"`
+		Exec []string `json:"exec"`
+	}
+
+	// PostmanVariable is a key and value such as a variable, header, query string
+	// parameter or auth parameter.
+	PostmanVariable struct {
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Type     string `json:"type,omitempty"`
+		Disabled bool   `json:"disabled,omitempty"`
+	}
+)
+
+// UnmarshalJSON reads a value which is not a string, such as a number or a
+// boolean, as its text.
+func (v *PostmanVariable) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Key      string      `json:"key"`
+		Value    interface{} `json:"value"`
+		Type     string      `json:"type"`
+		Disabled bool        `json:"disabled"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*v = PostmanVariable{Key: raw.Key, Type: raw.Type, Disabled: raw.Disabled}
+	switch value := raw.Value.(type) {
+	case nil:
+	case string:
+		v.Value = value
+	default:
+		v.Value = fmt.Sprint(value)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON reads a URL written as a string or as an object.
+func (u *PostmanURL) UnmarshalJSON(b []byte) error {
+	var raw string
+	if json.Unmarshal(b, &raw) == nil {
+		*u = PostmanURL{Raw: raw}
+		return nil
+	}
+
+	type plain PostmanURL
+	return json.Unmarshal(b, (*plain)(u))
+}
+
+// NewPostmanCollection builds a collection from the Definitions and test cases
+// of the entries. The `baseUrl` variable defaults to the host of the Client
+// used by in-process tests.
+func NewPostmanCollection(name string, entries []Entry) (*PostmanCollection, error) {
+	c := &PostmanCollection{
+		Info: PostmanInfo{Name: name, Schema: PostmanSchema},
+	}
+
+	baseURL := integrationClient.Hostname + integrationClient.BasePath
+	if integrationClient.Hostname == "" {
+		baseURL = "http://localhost" + integrationClient.BasePath
+	}
+	c.Variable = append(c.Variable, PostmanVariable{Key: "baseUrl", Value: baseURL, Type: "string"})
+
+	variables := map[string]bool{}
+	folders := map[string]int{}
+
+	for _, e := range entries {
+		def := e.Definition
+
+		folder := PostmanItem{
+			Name:        def.Name,
+			Description: def.Description,
+		}
+		if folder.Name == "" {
+			folder.Name = def.Method + " " + def.Path
+		}
+
+		auth, vars := postmanAuth(def)
+		folder.Auth = auth
+		for _, v := range vars {
+			if !variables[v] {
+				variables[v] = true
+				c.Variable = append(c.Variable, PostmanVariable{Key: v, Value: "", Type: "string"})
+			}
+		}
+		if def.Authentication == AuthenticationChecksum {
+			folder.Event = append(folder.Event, postmanSignatureEvent())
+		}
+
+		for _, tc := range e.Cases {
+			item, err := postmanItem(def, tc)
+			if err != nil {
+				return nil, err
+			}
+			folder.Item = append(folder.Item, item)
+		}
+
+		if def.Package == "" {
+			c.Item = append(c.Item, folder)
+			continue
+		}
+
+		i, ok := folders[def.Package]
+		if !ok {
+			i = len(c.Item)
+			folders[def.Package] = i
+			c.Item = append(c.Item, PostmanItem{Name: def.Package})
+		}
+		c.Item[i].Item = append(c.Item[i].Item, folder)
+	}
+
+	return c, nil
+}
+
+// postmanAuth returns the auth of the Definition and the variables it uses. The
+// type of the credentials registered for in-process tests decides the type of
+// auth and bearer tokens are used when none are registered.
+func postmanAuth(def Definition) (*PostmanAuth, []string) {
+	if !def.RequiresAuth() {
+		return &PostmanAuth{Type: "noauth"}, nil
+	}
+
+	key := def.Authentication
+	if key == "" {
+		key = AuthorizationCredentials
+	}
+
+	switch key {
+	case AuthorizationOpenID:
+		return &PostmanAuth{Type: "oauth2", OAuth2: []PostmanVariable{
+			{Key: "accessToken", Value: "{{accessToken}}", Type: "string"},
+			{Key: "addTokenTo", Value: "header", Type: "string"},
+		}}, []string{"accessToken"}
+	case AuthenticationChecksum:
+		// Requests are signed by a script. See postmanSignatureEvent.
+		return &PostmanAuth{Type: "noauth"}, []string{"signingKey"}
+	}
+
+	switch cred := integrationClient.Credentials[key].(type) {
+	case BasicAuth:
+		return &PostmanAuth{Type: "basic", Basic: []PostmanVariable{
+			{Key: "username", Value: "{{username}}", Type: "string"},
+			{Key: "password", Value: "{{password}}", Type: "string"},
+		}}, []string{"username", "password"}
+	case APIKey:
+		in := "header"
+		if cred.InQuery {
+			in = "query"
+		}
+		return &PostmanAuth{Type: "apikey", APIKey: []PostmanVariable{
+			{Key: "key", Value: cred.Name, Type: "string"},
+			{Key: "value", Value: "{{apiKey}}", Type: "string"},
+			{Key: "in", Value: in, Type: "string"},
+		}}, []string{"apiKey"}
+	}
+
+	return &PostmanAuth{Type: "bearer", Bearer: []PostmanVariable{
+		{Key: "token", Value: "{{token}}", Type: "string"},
+	}}, []string{"token"}
+}
+
+// postmanItem builds the request of a test case. The route variables of the
+// Definition become path variables valued from the path of the test case.
+func postmanItem(def Definition, tc *TestCase) (PostmanItem, error) {
+	path := tc.Path
+	if path == "" {
+		path = def.Path
+	}
+	path, query := splitPath(path)
+
+	u := PostmanURL{Host: []string{"{{baseUrl}}"}}
+
+	if params, ok := matchPath(def.Path, path); ok && len(params) > 0 {
+		for _, segment := range strings.Split(strings.TrimPrefix(def.Path, "/"), "/") {
+			if !isPathParam(segment) {
+				u.Path = append(u.Path, segment)
+				continue
+			}
+			name := pathParamName(segment)
+			u.Path = append(u.Path, ":"+name)
+			u.Variable = append(u.Variable, PostmanVariable{Key: name, Value: params[name]})
+		}
+	} else if path != "/" {
+		u.Path = strings.Split(strings.TrimPrefix(path, "/"), "/")
+	}
+
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v := pair, ""
+		if i := strings.Index(pair, "="); i >= 0 {
+			k, v = pair[:i], pair[i+1:]
+		}
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		u.Query = append(u.Query, PostmanVariable{Key: k, Value: v})
+	}
+
+	u.Raw = "{{baseUrl}}/" + strings.Join(u.Path, "/")
+	if query != "" {
+		u.Raw += "?" + query
+	}
+
+	req := &PostmanRequest{Method: def.Method, Header: []PostmanVariable{}, URL: u}
+
+	keys := make([]string, 0, len(tc.Headers))
+	for k := range tc.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		req.Header = append(req.Header, PostmanVariable{Key: k, Value: tc.Headers[k]})
+	}
+
+	if tc.Payload != nil {
+		body, err := marshalPayload(tc.Payload)
+		if err != nil {
+			return PostmanItem{}, fmt.Errorf("%s: Unable to encode payload: %s", tc.Name, err)
+		}
+		req.Body = &PostmanBody{Mode: "raw", Raw: string(body)}
+		if json.Valid(body) {
+			req.Body.Options = map[string]interface{}{"raw": map[string]string{"language": "json"}}
+			if _, ok := tc.Headers["Content-Type"]; !ok {
+				req.Header = append(req.Header, PostmanVariable{Key: "Content-Type", Value: MIMETypeJSON})
+			}
+		}
+	}
+
+	if tc.Identity == Anonymous {
+		req.Auth = &PostmanAuth{Type: "noauth"}
+	}
+
+	return PostmanItem{
+		Name:    tc.Name,
+		Request: req,
+		Event:   []PostmanEvent{{Listen: "test", Script: PostmanScript{Type: "text/javascript", Exec: postmanTests(tc)}}},
+	}, nil
+}
+
+// postmanTests returns a test script checking the status, the terms the body
+// contains and the expected body of the test case.
+func postmanTests(tc *TestCase) []string {
+	lines := []string{
+		fmt.Sprintf("pm.test(%s, function () {", JSON(fmt.Sprintf("Status is %d", statusOf(tc)))),
+		fmt.Sprintf("    pm.response.to.have.status(%d);", statusOf(tc)),
+		"});",
+	}
+
+	for _, term := range tc.Contains {
+		lines = append(lines,
+			fmt.Sprintf("pm.test(%s, function () {", JSON("Body contains "+term)),
+			fmt.Sprintf("    pm.expect(pm.response.text()).to.include(%s);", JSON(term)),
+			"});",
+		)
+	}
+
+	if tc.ExpectBody != nil {
+		lines = append(lines, `pm.test("Body equals the expected body", function () {`)
+		if json.Valid(tc.ExpectBody) {
+			lines = append(lines, fmt.Sprintf("    pm.expect(pm.response.json()).to.eql(%s);", JSON(json.RawMessage(tc.ExpectBody))))
+		} else {
+			lines = append(lines, fmt.Sprintf("    pm.expect(pm.response.text()).to.eql(%s);", JSON(string(tc.ExpectBody))))
+		}
+		lines = append(lines, "});")
+	}
+
+	return lines
+}
+
+// postmanSignatureScript signs requests the way a Signer does. HEADERS is
+// replaced by the headers the Signer signs.
+const postmanSignatureScript = `// Signs the request as truth.Signer does using the signingKey variable.
+const key = pm.variables.get("signingKey") || "";
+const headers = HEADERS;
+const esc = s => encodeURIComponent(s).replace(/%20/g, "+").replace(/[!'()*]/g, c => "%" + c.charCodeAt(0).toString(16).toUpperCase());
+let target = pm.variables.replaceIn(pm.request.url.toString()).replace(/^[a-z]+:\/\/[^\/?#]*/i, "");
+pm.request.url.variables.each(v => {
+    target = target.replace(new RegExp("/:" + v.key + "(?=[/?#]|$)"), "/" + encodeURIComponent(pm.variables.replaceIn(v.value)));
+});
+const [path, search = ""] = target.split("#")[0].split("?");
+const query = search.split("&").filter(p => p)
+    .map(p => p.split("=").map(s => decodeURIComponent(s.replace(/\+/g, " "))))
+    .sort((a, b) => a[0] !== b[0] ? (a[0] < b[0] ? -1 : 1) : (a[1] || "") < (b[1] || "") ? -1 : (a[1] || "") > (b[1] || "") ? 1 : 0)
+    .map(([k, v = ""]) => esc(k) + "=" + esc(v)).join("&");
+const lines = [pm.request.method, path || "/", query];
+headers.map(h => h.toLowerCase()).sort().forEach(h => {
+    const value = pm.request.headers.get(h);
+    lines.push(h + ":" + (value ? pm.variables.replaceIn(value).trim() : ""));
+});
+const body = pm.request.body && pm.request.body.mode === "raw" ? pm.variables.replaceIn(pm.request.body.raw) : "";
+const timestamp = Math.floor(Date.now() / 1000).toString();
+lines.push(CryptoJS.SHA256(body).toString(CryptoJS.enc.Hex), timestamp);
+pm.request.headers.upsert({ key: "X-Signature-Timestamp", value: timestamp });
+pm.request.headers.upsert({ key: "X-Signature", value: CryptoJS.HmacSHA256(lines.join("\n"), key).toString(CryptoJS.enc.Hex) });`
+
+// postmanSignatureEvent returns a pre-request script signing requests with the
+// headers of the Signer registered for in-process tests.
+func postmanSignatureEvent() PostmanEvent {
+	headers := []string{}
+	if s, ok := integrationClient.Credentials[AuthenticationChecksum].(*Signer); ok {
+		headers = append(headers, s.Headers...)
+	}
+
+	script := strings.Replace(postmanSignatureScript, "HEADERS", string(JSON(headers)), 1)
+
+	return PostmanEvent{Listen: "prerequest", Script: PostmanScript{Type: "text/javascript", Exec: strings.Split(script, "\n")}}
+}
+
+// ExportPostman writes a collection built from the registered Definitions to
+// the file at path.
+func ExportPostman(path, name string) error {
+	c, err := NewPostmanCollection(name, Registered())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.Write(f)
+}
+
+// Write serializes the collection as indented JSON.
+func (c *PostmanCollection) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// LoadPostman reads the Postman v2.0 or v2.1 collection at path.
+func LoadPostman(path string) (*PostmanCollection, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c PostmanCollection
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("Unable to read Postman collection %s: %s", path, err)
+	}
+
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "/v2.") {
+		return nil, fmt.Errorf("Postman collection %s uses the schema %#v but a v2 collection is required", path, c.Info.Schema)
+	}
+
+	return &c, nil
+}
+
+// Entries turns the requests of the collection into test cases. Requests are
+// grouped into Definitions by method and path, where path variables such as
+// `:id` become route variables, and each belongs to the Package of its top
+// level folder. A folder holding the requests of a single Definition names it.
+// Test scripts checking the status, the terms a body contains or the body
+// itself become the expectations of the test case. Credentials are left to the
+// Client so headers holding them are omitted.
+func (c *PostmanCollection) Entries() []Entry {
+	im := &postmanImporter{vars: map[string]string{}, index: map[string]int{}}
+	for _, v := range c.Variable {
+		im.vars[v.Key] = v.Value
+	}
+
+	for _, item := range c.Item {
+		im.item(item, "", c.Auth, c.Event)
+	}
+
+	return im.entries
+}
+
+type postmanImporter struct {
+	vars    map[string]string
+	entries []Entry
+	index   map[string]int
+}
+
+var (
+	postmanVariable = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+	postmanBaseURL  = regexp.MustCompile(`^{{\s*baseUrl\s*}}/?`)
+)
+
+// resolve replaces the collection variables within s. Unknown variables are
+// left as they are.
+func (im *postmanImporter) resolve(s string) string {
+	return postmanVariable.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := im.vars[postmanVariable.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// item imports a request or the requests within a folder. Auth and events are
+// inherited from the enclosing folders.
+func (im *postmanImporter) item(item PostmanItem, pkg string, auth *PostmanAuth, events []PostmanEvent) {
+	if item.Request == nil {
+		top := pkg == ""
+		if top {
+			pkg = item.Name
+		}
+		if item.Auth != nil {
+			auth = item.Auth
+		}
+		events = append(append([]PostmanEvent(nil), events...), item.Event...)
+
+		keys := map[string]bool{}
+		for _, child := range item.Item {
+			if child.Request == nil {
+				keys = nil
+			}
+			im.item(child, pkg, auth, events)
+			if child.Request != nil && keys != nil {
+				method, template, _ := im.request(child.Request)
+				keys[method+":"+template] = true
+			}
+		}
+
+		// A folder holding the requests of one Definition names it rather than a
+		// Package.
+		if len(keys) == 1 {
+			for k := range keys {
+				def := &im.entries[im.index[k]].Definition
+				if def.Name == "" && item.Name != strings.Replace(k, ":", " ", 1) {
+					def.Name = item.Name
+					def.Description = item.Description
+				}
+				if top {
+					def.Package = ""
+				}
+			}
+		}
+		return
+	}
+
+	method, template, path := im.request(item.Request)
+
+	def := Definition{
+		Method:         method,
+		Path:           template,
+		Package:        pkg,
+		Authentication: postmanAuthentication(auth, events),
+	}
+
+	tc := &TestCase{Name: item.Name, Path: path}
+
+	if item.Request.Auth != nil {
+		a := postmanAuthentication(item.Request.Auth, nil)
+		if a == AuthorizationNone && def.RequiresAuth() {
+			// A request sent without the auth of its folder is anonymous.
+			tc.Identity = Anonymous
+		} else {
+			def.Authentication = a
+		}
+	}
+	def.Authenticated = def.RequiresAuth()
+
+	for _, h := range item.Request.Header {
+		if h.Disabled || redactedHeader(h.Key) {
+			continue
+		}
+		if tc.Headers == nil {
+			tc.Headers = map[string]string{}
+		}
+		tc.Headers[http.CanonicalHeaderKey(h.Key)] = im.resolve(h.Value)
+	}
+
+	if b := item.Request.Body; b != nil {
+		switch b.Mode {
+		case "raw":
+			if b.Raw != "" {
+				tc.Payload = []byte(im.resolve(b.Raw))
+			}
+		case "urlencoded":
+			var pairs []string
+			for _, p := range b.URLEncoded {
+				if !p.Disabled {
+					pairs = append(pairs, url.QueryEscape(im.resolve(p.Key))+"="+url.QueryEscape(im.resolve(p.Value)))
+				}
+			}
+			tc.Payload = []byte(strings.Join(pairs, "&"))
+			if _, ok := tc.Headers["Content-Type"]; !ok {
+				if tc.Headers == nil {
+					tc.Headers = map[string]string{}
+				}
+				tc.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+			}
+		}
+	}
+
+	for _, e := range item.Event {
+		if e.Listen == "test" {
+			postmanExpectations(tc, e.Script.Exec)
+		}
+	}
+
+	key := def.Method + ":" + def.Path
+	i, ok := im.index[key]
+	if !ok {
+		i = len(im.entries)
+		im.index[key] = i
+		im.entries = append(im.entries, Entry{Definition: def})
+	}
+	im.entries[i].Cases = append(im.entries[i].Cases, tc)
+}
+
+// request returns the method of the request, the path template of its
+// Definition and the path of its test case.
+func (im *postmanImporter) request(r *PostmanRequest) (method, template, path string) {
+	method = strings.ToUpper(r.Method)
+	if method == "" {
+		method = GET
+	}
+
+	u := r.URL
+	segments := u.Path
+	query := ""
+	if len(segments) == 0 {
+		// The base URL is dropped before resolving variables so a path it
+		// holds does not become part of the Definition.
+		raw := im.resolve(postmanBaseURL.ReplaceAllString(u.Raw, "/"))
+		if i := strings.Index(raw, "#"); i >= 0 {
+			raw = raw[:i]
+		}
+		raw, query = splitPath(raw)
+		if i := strings.Index(raw, "://"); i >= 0 {
+			raw = raw[i+3:]
+		}
+		if i := strings.Index(raw, "/"); i >= 0 {
+			segments = strings.Split(raw[i+1:], "/")
+		}
+	}
+
+	values := map[string]string{}
+	for _, v := range u.Variable {
+		values[v.Key] = im.resolve(v.Value)
+	}
+
+	var tmpl, concrete []string
+	for _, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			name := s[1:]
+			value := values[name]
+			if value == "" {
+				value = name
+			}
+			tmpl = append(tmpl, "{"+name+"}")
+			concrete = append(concrete, url.PathEscape(value))
+			continue
+		}
+		s = im.resolve(s)
+		tmpl = append(tmpl, s)
+		concrete = append(concrete, s)
+	}
+
+	template = "/" + strings.Join(tmpl, "/")
+	path = "/" + strings.Join(concrete, "/")
+
+	if len(u.Path) > 0 || query == "" {
+		var pairs []string
+		for _, q := range u.Query {
+			if q.Disabled {
+				continue
+			}
+			pair := url.QueryEscape(im.resolve(q.Key))
+			if q.Value != "" {
+				pair += "=" + url.QueryEscape(im.resolve(q.Value))
+			}
+			pairs = append(pairs, pair)
+		}
+		query = strings.Join(pairs, "&")
+	}
+	if query != "" {
+		path += "?" + query
+	}
+
+	return method, template, path
+}
+
+// postmanAuthentication returns the Authentication of requests using the auth
+// and events.
+func postmanAuthentication(auth *PostmanAuth, events []PostmanEvent) string {
+	for _, e := range events {
+		if e.Listen == "prerequest" && strings.Contains(strings.Join(e.Script.Exec, "\n"), SignatureHeader) {
+			return AuthenticationChecksum
+		}
+	}
+
+	switch {
+	case auth == nil:
+		return ""
+	case auth.Type == "noauth":
+		return AuthorizationNone
+	case auth.Type == "oauth2":
+		return AuthorizationOpenID
+	}
+	return AuthorizationCredentials
+}
+
+var (
+	postmanStatus   = regexp.MustCompile(`pm\.response\.(?:to\.have\.status\(|code\)\.to\.(?:eql|equal)\()(\d{3})\)`)
+	postmanContains = regexp.MustCompile(`pm\.expect\(pm\.response\.text\(\)\)\.to\.include\(("(?:[^"\\]|\\.)*")\)`)
+	postmanJSONBody = regexp.MustCompile(`pm\.expect\(pm\.response\.json\(\)\)\.to\.eql\((.*)\);\s*$`)
+	postmanTextBody = regexp.MustCompile(`pm\.expect\(pm\.response\.text\(\)\)\.to\.eql\(("(?:[^"\\]|\\.)*")\)`)
+)
+
+// postmanExpectations sets the expectations of the test case checked by a test
+// script.
+func postmanExpectations(tc *TestCase, exec []string) {
+	for _, line := range exec {
+		if m := postmanStatus.FindStringSubmatch(line); m != nil {
+			fmt.Sscan(m[1], &tc.Status)
+		}
+		for _, m := range postmanContains.FindAllStringSubmatch(line, -1) {
+			var term string
+			if json.Unmarshal([]byte(m[1]), &term) == nil {
+				tc.Contains = append(tc.Contains, term)
+			}
+		}
+		if m := postmanJSONBody.FindStringSubmatch(line); m != nil && json.Valid([]byte(m[1])) {
+			tc.ExpectBody = []byte(m[1])
+		}
+		if m := postmanTextBody.FindStringSubmatch(line); m != nil {
+			var text string
+			if json.Unmarshal([]byte(m[1]), &text) == nil {
+				tc.ExpectBody = []byte(text)
+			}
+		}
+	}
+}
+
+// redactedHeader reports if the header is one of the DefaultRedactHeaders.
+func redactedHeader(name string) bool {
+	for _, h := range DefaultRedactHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
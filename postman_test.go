@@ -0,0 +1,163 @@
+package truth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPostmanRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+	}{
+		{
+			name: "route variables",
+			entry: Entry{
+				Definition: Definition{Method: "GET", Path: "/users/{id}", Package: "users", Name: "Get user", Authenticated: true},
+				Cases: TestCases{
+					{Name: "found", Path: "/users/1?fields=name", Status: 200, ExpectBody: []byte(`{"id":1,"name":"Ann"}`)},
+					{Name: "missing", Path: "/users/2", Status: 404, Contains: []string{"not found"}},
+					{Name: "anonymous", Path: "/users/1", Status: 401, Identity: Anonymous},
+				},
+			},
+		},
+		{
+			name: "payload",
+			entry: Entry{
+				Definition: Definition{Method: "POST", Path: "/sessions"},
+				Cases: TestCases{
+					{Name: "login", Path: "/sessions", Headers: map[string]string{"Accept-Language": "en", "Content-Type": MIMETypeJSON}, Payload: []byte(`{"user":"ann"}`), Status: 201},
+					{Name: "text", Path: "/sessions", Headers: map[string]string{"Content-Type": "text/plain"}, Payload: []byte("ann"), Status: 400, ExpectBody: []byte("invalid")},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewPostmanCollection("api", []Entry{test.entry})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			if err := c.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			var loaded PostmanCollection
+			if err := json.Unmarshal(b.Bytes(), &loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := loaded.Entries()
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry but received %d:\n%s", len(entries), b.String())
+			}
+
+			def, expected := entries[0].Definition, test.entry.Definition
+			if def.Method != expected.Method || def.Path != expected.Path || def.Package != expected.Package || def.Name != expected.Name || def.Authenticated != expected.Authenticated {
+				t.Errorf("Expected %s but received %s", JSON(expected), JSON(def))
+			}
+			if len(entries[0].Cases) != len(test.entry.Cases) {
+				t.Fatalf("Expected %d test cases but received %d", len(test.entry.Cases), len(entries[0].Cases))
+			}
+			for i, tc := range entries[0].Cases {
+				if actual, expected := postmanCase(tc), postmanCase(test.entry.Cases[i]); actual != expected {
+					t.Errorf("Expected %s but received %s", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestPostmanEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		vars     string
+		method   string
+		template string
+		path     string
+		headers  string
+	}{
+		{
+			name:     "string url",
+			request:  `{"method":"get","url":"{{baseUrl}}/users/{{userId}}?fields=name"}`,
+			vars:     `[{"key":"baseUrl","value":"https://api.example.com/v1"},{"key":"userId","value":"7"}]`,
+			method:   "GET",
+			template: "/users/7",
+			path:     "/users/7?fields=name",
+			headers:  `null`,
+		},
+		{
+			name:     "string url with host",
+			request:  `{"method":"DELETE","url":"https://api.example.com/users/1#top"}`,
+			vars:     `[]`,
+			method:   "DELETE",
+			template: "/users/1",
+			path:     "/users/1",
+			headers:  `null`,
+		},
+		{
+			name:     "path variables",
+			request:  `{"method":"PUT","url":{"raw":"{{baseUrl}}/users/:id","path":["users",":id"],"variable":[{"key":"id","value":"{{userId}}"}],"query":[{"key":"a","value":"1"},{"key":"b","value":"2","disabled":true}]}}`,
+			vars:     `[{"key":"baseUrl","value":"https://api.example.com/v1"},{"key":"userId","value":"7"}]`,
+			method:   "PUT",
+			template: "/users/{id}",
+			path:     "/users/7?a=1",
+			headers:  `null`,
+		},
+		{
+			name:     "credential headers",
+			request:  `{"method":"GET","url":"{{baseUrl}}/","header":[{"key":"authorization","value":"Bearer {{token}}"},{"key":"x-api-key","value":"secret"},{"key":"cookie","value":"a=b"},{"key":"x-trace","value":"{{trace}}"},{"key":"x-off","value":"1","disabled":true}]}`,
+			vars:     `[{"key":"trace","value":"abc"}]`,
+			method:   "GET",
+			template: "/",
+			path:     "/",
+			headers:  `{"X-Trace":"abc"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := `{"info":{"schema":"` + PostmanSchema + `"},"variable":` + test.vars + `,"item":[{"name":"request","request":` + test.request + `}]}`
+
+			var c PostmanCollection
+			if err := json.Unmarshal([]byte(doc), &c); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := c.Entries()
+			if len(entries) != 1 || len(entries[0].Cases) != 1 {
+				t.Fatalf("Expected 1 entry with 1 test case but received %s", JSON(entries))
+			}
+
+			def, tc := entries[0].Definition, entries[0].Cases[0]
+			if def.Method != test.method || def.Path != test.template {
+				t.Errorf("Expected %s %s but received %s %s", test.method, test.template, def.Method, def.Path)
+			}
+			if tc.Path != test.path {
+				t.Errorf("Expected path %s but received %s", test.path, tc.Path)
+			}
+			if actual := string(JSON(tc.Headers)); actual != test.headers {
+				t.Errorf("Expected headers %s but received %s", test.headers, actual)
+			}
+		})
+	}
+}
+
+func TestLoadPostmanRejectsOtherSchemas(t *testing.T) {
+	path := writeTemp(t, "collection.json", []byte(`{"info":{"schema":"https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`))
+	if _, err := LoadPostman(path); err == nil || !strings.Contains(err.Error(), "v2") {
+		t.Errorf("Expected a v1 collection to be rejected but received %v", err)
+	}
+}
+
+// postmanCase describes the parts of a test case held by a Postman request.
+func postmanCase(tc *TestCase) string {
+	payload, _ := tc.Payload.([]byte)
+	return fmt.Sprintf("%s %s %s %q %d %q %q %q", tc.Name, tc.Path, JSON(tc.Headers), payload, tc.Status, tc.ExpectBody, tc.Contains, tc.Identity)
+}
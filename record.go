@@ -0,0 +1,500 @@
+package truth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces secrets within recorded exchanges.
+const Redacted = "REDACTED"
+
+type (
+	// Exchange is a request and the response it received.
+	Exchange struct {
+		Method string
+		// Host is the scheme and host the request was sent to such as
+		// http://localhost:8080.
+		Host            string
+		URL             string // The path and query string of the request.
+		RequestHeaders  http.Header
+		RequestBody     []byte
+		Status          int
+		ResponseHeaders http.Header
+		ResponseBody    []byte
+		Started         time.Time
+		Duration        time.Duration
+	}
+
+	// RecordingProxy is a reverse proxy which records the exchanges it forwards
+	// to a target so live traffic can become test cases. Exchanges are matched to
+	// Definitions by method and path template. Secrets held by headers and JSON
+	// fields are redacted as they are recorded.
+	//
+	//	p, err := truth.NewRecordingProxy("http://localhost:8080", truth.Registered())
+	//	...
+	//	http.ListenAndServe(":8081", p)
+	RecordingProxy struct {
+		// RedactHeaders and RedactFields name the headers and the fields, of the
+		// query string, form bodies and JSON bodies at any depth, whose values are
+		// replaced with Redacted. Names are case-insensitive. The defaults are
+		// DefaultRedactHeaders and DefaultRedactFields.
+		RedactHeaders []string
+		RedactFields  []string
+
+		proxy     *httputil.ReverseProxy
+		target    string
+		entries   []Entry
+		mu        sync.Mutex
+		exchanges []Exchange
+	}
+
+	// Recording matches exchanges, whether recorded by a RecordingProxy or read
+	// from a HAR file, to Definitions by method and path template so they can be
+	// written as test cases. Without Entries exchanges are grouped by their method
+	// and path.
+	//
+	//	exchanges, err := truth.LoadHAR("bug-report.har")
+	//	...
+	//	r := truth.Recording{Exchanges: exchanges, Entries: truth.Registered()}
+	//	r.WriteGo(f, "api")
+	Recording struct {
+		Exchanges []Exchange
+		Entries   []Entry
+	}
+)
+
+// DefaultRedactHeaders are the headers redacted by a RecordingProxy and when
+// exchanges are read from or written to HAR files. They are the
+// CredentialHeaders.
+var DefaultRedactHeaders = CredentialHeaders
+
+// DefaultRedactFields are the fields of query strings, form bodies and JSON
+// bodies redacted by a RecordingProxy and when exchanges are read from or
+// written to HAR files.
+var DefaultRedactFields = []string{"password", "secret", "token", "access_token", "refresh_token", "id_token", "client_secret", "api_key"}
+
+// NewRecordingProxy returns a proxy forwarding to the target and matching the
+// exchanges it records to the entries.
+func NewRecordingProxy(target string, entries []Entry) (*RecordingProxy, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Target %#v must be an absolute URL", target)
+	}
+
+	p := &RecordingProxy{
+		RedactHeaders: DefaultRedactHeaders,
+		RedactFields:  DefaultRedactFields,
+		target:        u.Scheme + "://" + u.Host,
+		entries:       entries,
+	}
+
+	p.proxy = httputil.NewSingleHostReverseProxy(u)
+	director := p.proxy.Director
+	p.proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = u.Host
+		// The transport negotiates compression itself so recorded bodies are plain.
+		req.Header.Del("Accept-Encoding")
+	}
+
+	return p, nil
+}
+
+// ServeHTTP forwards the request to the target and records the exchange.
+func (p *RecordingProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	x := Exchange{
+		Method:         req.Method,
+		Host:           p.target,
+		URL:            req.URL.RequestURI(),
+		RequestHeaders: req.Header.Clone(),
+		Started:        time.Now(),
+	}
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadGateway)
+			return
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		x.RequestBody = body
+	}
+
+	rec := &recordingWriter{ResponseWriter: rw, status: http.StatusOK}
+	p.proxy.ServeHTTP(rec, req)
+
+	x.Duration = time.Since(x.Started)
+	x.Status = rec.status
+	x.ResponseHeaders = rw.Header().Clone()
+	x.ResponseBody = rec.body.Bytes()
+
+	p.mu.Lock()
+	p.exchanges = append(p.exchanges, redact(x, p.RedactHeaders, p.RedactFields))
+	p.mu.Unlock()
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Exchanges returns the exchanges recorded so far.
+func (p *RecordingProxy) Exchanges() []Exchange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Exchange(nil), p.exchanges...)
+}
+
+// Recording returns the exchanges recorded so far matched to the entries of
+// the proxy.
+func (p *RecordingProxy) Recording() *Recording {
+	return &Recording{Exchanges: p.Exchanges(), Entries: p.entries}
+}
+
+// Recorded returns an Entry holding test cases built from the exchanges for
+// every Definition which received traffic.
+func (r *Recording) Recorded() []Entry {
+	var out []Entry
+	index := map[string]int{}
+
+	for _, x := range r.Exchanges {
+		def, ok := r.match(x)
+		if !ok {
+			continue
+		}
+
+		key := def.Method + ":" + def.Path
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, Entry{Definition: def})
+		}
+
+		out[i].Cases = append(out[i].Cases, x.TestCase(fmt.Sprintf("%s %s recorded #%d", def.Method, def.Path, len(out[i].Cases)+1)))
+	}
+
+	return out
+}
+
+// Undocumented returns the method and path of every request which did not
+// match a Definition.
+func (r *Recording) Undocumented() []string {
+	seen := map[string]bool{}
+	var out []string
+
+	for _, x := range r.Exchanges {
+		if _, ok := r.match(x); ok {
+			continue
+		}
+		path, _ := splitPath(x.URL)
+		k := x.Method + " " + path
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+func (r *Recording) match(x Exchange) (Definition, bool) {
+	if len(r.Entries) == 0 {
+		path, _ := splitPath(x.URL)
+		return Definition{Method: x.Method, Path: path}, true
+	}
+
+	for _, e := range r.Entries {
+		if e.Definition.Method != x.Method {
+			continue
+		}
+		if _, ok := matchPath(e.Definition.Path, x.URL); ok {
+			return e.Definition, true
+		}
+	}
+	return Definition{}, false
+}
+
+// redact replaces the values of the headers of the exchange and of the fields
+// of its query string, JSON bodies and form bodies. The headers of the exchange
+// are copied rather than changed.
+func redact(x Exchange, headers, fields []string) Exchange {
+	x.RequestHeaders = x.RequestHeaders.Clone()
+	x.ResponseHeaders = x.ResponseHeaders.Clone()
+	for _, h := range headers {
+		for _, header := range []http.Header{x.RequestHeaders, x.ResponseHeaders} {
+			if _, ok := header[http.CanonicalHeaderKey(h)]; ok {
+				header.Set(h, Redacted)
+			}
+		}
+	}
+
+	redacted := map[string]bool{}
+	for _, f := range fields {
+		redacted[strings.ToLower(f)] = true
+	}
+	x.URL = redactQuery(x.URL, redacted)
+	x.RequestBody = redactBody(x.RequestBody, x.RequestHeaders, redacted)
+	x.ResponseBody = redactBody(x.ResponseBody, x.ResponseHeaders, redacted)
+
+	return x
+}
+
+// redactQuery replaces the values of the fields in the query string of the URL.
+func redactQuery(rawURL string, fields map[string]bool) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	q, changed := redactValues(u.Query(), fields)
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactBody replaces the values of the fields of a form body, as told by the
+// Content-Type header, or of a JSON body.
+func redactBody(body []byte, header http.Header, fields map[string]bool) []byte {
+	if !strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return redactJSON(body, fields)
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	if form, changed := redactValues(form, fields); changed {
+		return []byte(form.Encode())
+	}
+	return body
+}
+
+// redactValues replaces the values of the fields and reports if any were.
+func redactValues(v url.Values, fields map[string]bool) (url.Values, bool) {
+	changed := false
+	for k := range v {
+		if fields[strings.ToLower(k)] {
+			v.Set(k, Redacted)
+			changed = true
+		}
+	}
+	return v, changed
+}
+
+// redactNames returns the headers and fields redacted from the exchanges made
+// by the Client, which defaults to the Client of the package. They are the
+// DefaultRedactHeaders and DefaultRedactFields and the names of the API keys
+// registered with the Client.
+func redactNames(c *Client) (headers, fields []string) {
+	if c == nil {
+		c = integrationClient
+	}
+
+	headers = append(append([]string(nil), DefaultRedactHeaders...), c.headerCredentials()...)
+	fields = append(append([]string(nil), DefaultRedactFields...), c.queryCredentials()...)
+	return headers, fields
+}
+
+// redactJSON replaces the values of the fields at any depth of a JSON body.
+// Other bodies are returned as they are.
+func redactJSON(body []byte, fields map[string]bool) []byte {
+	var v interface{}
+	if len(fields) == 0 || json.Unmarshal(body, &v) != nil {
+		return body
+	}
+
+	var walk func(v interface{}) bool
+	walk = func(v interface{}) bool {
+		changed := false
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, e := range v {
+				if fields[strings.ToLower(k)] {
+					v[k] = Redacted
+					changed = true
+					continue
+				}
+				changed = walk(e) || changed
+			}
+		case []interface{}:
+			for _, e := range v {
+				changed = walk(e) || changed
+			}
+		}
+		return changed
+	}
+
+	if !walk(v) {
+		return body
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// omittedHeaders are request headers which describe the transport or the
+// recording client rather than the request so test cases omit them.
+var omittedHeaders = map[string]bool{
+	"Accept-Encoding": true, "Connection": true, "Content-Length": true,
+	"Keep-Alive": true, "Transfer-Encoding": true, "User-Agent": true,
+	"X-Forwarded-For": true, "X-Forwarded-Host": true, "X-Forwarded-Proto": true,
+}
+
+// TestCase returns a test case reproducing the exchange with a snapshot of the
+// response body. Redacted headers are omitted as credentials are provided by
+// the Client. A response body with redacted fields cannot be matched exactly so
+// the test case expects the response to contain the fields of the body instead.
+func (x Exchange) TestCase(name string) *TestCase {
+	tc := &TestCase{
+		Name:   name,
+		Path:   x.URL,
+		Status: x.Status,
+	}
+
+	for k, v := range x.RequestHeaders {
+		if omittedHeaders[k] || len(v) == 0 || v[0] == Redacted {
+			continue
+		}
+		if tc.Headers == nil {
+			tc.Headers = map[string]string{}
+		}
+		tc.Headers[k] = v[0]
+	}
+
+	if len(x.RequestBody) > 0 {
+		tc.Payload = x.RequestBody
+	}
+	switch {
+	case len(x.ResponseBody) == 0:
+	case bytes.Contains(x.ResponseBody, []byte(strconv.Quote(Redacted))):
+		var v interface{}
+		if json.Unmarshal(x.ResponseBody, &v) == nil {
+			tc.Contains = fieldTerms(v)
+		}
+	default:
+		tc.ExpectBody = x.ResponseBody
+	}
+
+	return tc
+}
+
+// WriteGo writes the recorded test cases as Go source for the package. A
+// variable is declared for every Definition which received traffic.
+func (r *Recording) WriteGo(w io.Writer, pkg string) error {
+	var src bytes.Buffer
+
+	fmt.Fprintf(&src, "// Test cases recorded from live traffic by truth.\n\npackage %s\n\n", pkg)
+	fmt.Fprintf(&src, "import \"github.com/aarongreenlee/truth\"\n\n")
+
+	names := map[string]bool{}
+	for _, e := range r.Recorded() {
+		def := e.Definition
+
+		name := def.Name
+		if name == "" {
+			name = operationName(def.Method, def.Path)
+		}
+		base := goName(name) + "RecordedCases"
+		name = base
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		names[name] = true
+
+		fmt.Fprintf(&src, "// %s were recorded for `%s %s`.\n", name, def.Method, def.Path)
+		fmt.Fprintf(&src, "var %s = truth.TestCases{\n", name)
+		for _, tc := range e.Cases {
+			writeGoCase(&src, tc)
+		}
+		src.WriteString("}\n\n")
+	}
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("Unable to format the generated source: %s", err)
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+func writeGoCase(w io.Writer, tc *TestCase) {
+	fmt.Fprintf(w, "{\nName: %q,\nPath: %q,\n", tc.Name, tc.Path)
+
+	if len(tc.Headers) > 0 {
+		keys := make([]string, 0, len(tc.Headers))
+		for k := range tc.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(w, "Headers: map[string]string{\n")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%q: %q,\n", k, tc.Headers[k])
+		}
+		fmt.Fprintf(w, "},\n")
+	}
+
+	if body, ok := tc.Payload.([]byte); ok {
+		fmt.Fprintf(w, "Payload: []byte(%s),\n", goString(string(body)))
+	}
+	fmt.Fprintf(w, "Status: %d,\n", tc.Status)
+	if tc.ExpectBody != nil {
+		fmt.Fprintf(w, "ExpectBody: []byte(%s),\n", goString(string(tc.ExpectBody)))
+	}
+	if len(tc.Contains) > 0 {
+		terms := make([]string, len(tc.Contains))
+		for i, q := range tc.Contains {
+			terms[i] = goString(q)
+		}
+		fmt.Fprintf(w, "Contains: []string{%s},\n", strings.Join(terms, ", "))
+	}
+
+	fmt.Fprintf(w, "},\n")
+}
+
+// WriteYAML writes the recorded test cases as file tests. See RunFileTests.
+func (r *Recording) WriteYAML(w io.Writer) error {
+	return writeFileTests(w, r.Recorded(), true)
+}
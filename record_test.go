@@ -0,0 +1,323 @@
+package truth
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		redacted bool
+	}{
+		{name: "authorization", header: "Authorization", redacted: true},
+		{name: "proxy authorization", header: "Proxy-Authorization", redacted: true},
+		{name: "cookie", header: "Cookie", redacted: true},
+		{name: "set cookie", header: "Set-Cookie", redacted: true},
+		{name: "api key", header: "x-api-key", redacted: true},
+		{name: "auth token", header: "X-Auth-Token", redacted: true},
+		{name: "signature", header: SignatureHeader, redacted: true},
+		{name: "other", header: "X-Request-Id"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(test.header, "secret")
+
+			x := redact(Exchange{RequestHeaders: h, ResponseHeaders: h.Clone()}, CredentialHeaders, nil)
+			for _, header := range []http.Header{x.RequestHeaders, x.ResponseHeaders} {
+				if actual := header.Get(test.header) == Redacted; actual != test.redacted {
+					t.Errorf("Expected redacted to be %t but received %q", test.redacted, header.Get(test.header))
+				}
+			}
+		})
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded; charset=utf-8"}}
+	json := http.Header{"Content-Type": {MIMETypeJSON}}
+
+	tests := []struct {
+		name     string
+		x        Exchange
+		expected Exchange
+	}{
+		{
+			name:     "query",
+			x:        Exchange{URL: "/search?q=ann&api_key=abc&Token=def"},
+			expected: Exchange{URL: "/search?Token=REDACTED&api_key=REDACTED&q=ann"},
+		},
+		{
+			name:     "custom query",
+			x:        Exchange{URL: "/search?sig=abc&q=ann"},
+			expected: Exchange{URL: "/search?q=ann&sig=REDACTED"},
+		},
+		{
+			name:     "untouched query",
+			x:        Exchange{URL: "/search?q=ann&page=2"},
+			expected: Exchange{URL: "/search?q=ann&page=2"},
+		},
+		{
+			name:     "form",
+			x:        Exchange{RequestHeaders: form, RequestBody: []byte("username=ann&password=hunter2")},
+			expected: Exchange{RequestHeaders: form, RequestBody: []byte("password=REDACTED&username=ann")},
+		},
+		{
+			name:     "form response",
+			x:        Exchange{ResponseHeaders: form, ResponseBody: []byte("access_token=abc&expires_in=60")},
+			expected: Exchange{ResponseHeaders: form, ResponseBody: []byte("access_token=REDACTED&expires_in=60")},
+		},
+		{
+			name:     "json",
+			x:        Exchange{RequestHeaders: json, RequestBody: []byte(`{"password":"hunter2"}`)},
+			expected: Exchange{RequestHeaders: json, RequestBody: []byte(`{"password":"REDACTED"}`)},
+		},
+		{
+			name:     "form without a content type",
+			x:        Exchange{RequestBody: []byte("password=hunter2")},
+			expected: Exchange{RequestBody: []byte("password=hunter2")},
+		},
+	}
+
+	fields := append([]string{"sig"}, DefaultRedactFields...)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := redact(test.x, nil, fields)
+			if actual.URL != test.expected.URL {
+				t.Errorf("Expected URL %s but received %s", test.expected.URL, actual.URL)
+			}
+			if string(actual.RequestBody) != string(test.expected.RequestBody) {
+				t.Errorf("Expected request body %s but received %s", test.expected.RequestBody, actual.RequestBody)
+			}
+			if string(actual.ResponseBody) != string(test.expected.ResponseBody) {
+				t.Errorf("Expected response body %s but received %s", test.expected.ResponseBody, actual.ResponseBody)
+			}
+		})
+	}
+}
+
+func TestRedactCopiesHeaders(t *testing.T) {
+	h := http.Header{"Authorization": {"Bearer secret"}}
+	x := redact(Exchange{RequestHeaders: h}, CredentialHeaders, nil)
+
+	if x.RequestHeaders.Get("Authorization") != Redacted {
+		t.Errorf("Expected the header to be redacted but received %s", x.RequestHeaders.Get("Authorization"))
+	}
+	if h.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Expected the headers of the exchange to be left unchanged but received %s", h.Get("Authorization"))
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "top level", body: `{"user":"ann","password":"hunter2"}`, expected: `{"password":"REDACTED","user":"ann"}`},
+		{name: "nested", body: `{"session":{"Access_Token":"abc","expires":60}}`, expected: `{"session":{"Access_Token":"REDACTED","expires":60}}`},
+		{name: "array", body: `[{"token":"a"},{"token":"b"}]`, expected: `[{"token":"REDACTED"},{"token":"REDACTED"}]`},
+		{name: "object value", body: `{"secret":{"key":"a"}}`, expected: `{"secret":"REDACTED"}`},
+		{name: "untouched", body: "{\"user\": \"ann\"}\n", expected: "{\"user\": \"ann\"}\n"},
+		{name: "not json", body: "password=hunter2", expected: "password=hunter2"},
+	}
+
+	fields := map[string]bool{}
+	for _, f := range DefaultRedactFields {
+		fields[f] = true
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := string(redactJSON([]byte(test.body), fields)); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExchangeTestCase(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		expectBody string
+		contains   []string
+	}{
+		{name: "empty body"},
+		{name: "body", body: `{"id":1}`, expectBody: `{"id":1}`},
+		{name: "redacted body", body: `{"id":1,"token":"REDACTED"}`, contains: []string{`"id"`, `"token"`}},
+		{name: "redacted list", body: `[{"token":"REDACTED"}]`, contains: []string{`"token"`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			x := Exchange{
+				Method:         "POST",
+				URL:            "/sessions?next=%2F",
+				RequestHeaders: http.Header{"Authorization": {Redacted}, "User-Agent": {"curl"}, "Accept-Language": {"en"}},
+				RequestBody:    []byte(`{"user":"ann"}`),
+				Status:         201,
+				ResponseBody:   []byte(test.body),
+			}
+
+			tc := x.TestCase("login")
+			if tc.Path != x.URL || tc.Status != 201 || string(tc.Payload.([]byte)) != `{"user":"ann"}` {
+				t.Errorf("Expected the request to be reproduced but received %+v", tc)
+			}
+			if actual := JSON(tc.Headers); string(actual) != `{"Accept-Language":"en"}` {
+				t.Errorf("Expected redacted and transport headers to be omitted but received %s", actual)
+			}
+			if string(tc.ExpectBody) != test.expectBody {
+				t.Errorf("Expected ExpectBody %q but received %q", test.expectBody, tc.ExpectBody)
+			}
+			if actual, expected := JSON(tc.Contains), JSON(test.contains); string(actual) != string(expected) {
+				t.Errorf("Expected Contains %s but received %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRecordingRecorded(t *testing.T) {
+	exchanges := []Exchange{
+		{Method: "GET", URL: "/users/1", Status: 200},
+		{Method: "GET", URL: "/users/2?fields=name", Status: 200},
+		{Method: "DELETE", URL: "/users/1", Status: 204},
+		{Method: "GET", URL: "/orders", Status: 200},
+	}
+
+	tests := []struct {
+		name         string
+		entries      []Entry
+		expected     []string
+		undocumented []string
+	}{
+		{
+			name:     "without definitions",
+			expected: []string{"GET /users/1 1", "GET /users/2 1", "DELETE /users/1 1", "GET /orders 1"},
+		},
+		{
+			name:         "definitions",
+			entries:      []Entry{{Definition: Definition{Method: "GET", Path: "/users/{id}"}}},
+			expected:     []string{"GET /users/{id} 2"},
+			undocumented: []string{"DELETE /users/1", "GET /orders"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &Recording{Exchanges: exchanges, Entries: test.entries}
+
+			var actual []string
+			for _, e := range r.Recorded() {
+				actual = append(actual, fmt.Sprintf("%s %s %d", e.Definition.Method, e.Definition.Path, len(e.Cases)))
+			}
+			if string(JSON(actual)) != string(JSON(test.expected)) {
+				t.Errorf("Expected %v but received %v", test.expected, actual)
+			}
+			if undocumented := r.Undocumented(); string(JSON(undocumented)) != string(JSON(test.undocumented)) {
+				t.Errorf("Expected undocumented %v but received %v", test.undocumented, undocumented)
+			}
+		})
+	}
+}
+
+func TestRecordingWrite(t *testing.T) {
+	r := &Recording{Exchanges: []Exchange{
+		{Method: "GET", URL: "/users/1", Status: 200, ResponseBody: []byte(`{"id":1,"name":"Ann"}`)},
+		{Method: "POST", URL: "/sessions", Status: 201, RequestBody: []byte(`{"user":"ann"}`), ResponseBody: []byte(`{"token":"REDACTED"}`)},
+	}}
+
+	var src bytes.Buffer
+	if err := r.WriteGo(&src, "api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "recorded.go", src.Bytes(), 0); err != nil {
+		t.Fatalf("Expected valid Go source but received %s:\n%s", err, src.String())
+	}
+	for _, expected := range []string{"GetUsers1RecordedCases", "ExpectBody: []byte(`{\"id\":1,\"name\":\"Ann\"}`)", "Contains: []string{`\"token\"`}"} {
+		if !strings.Contains(src.String(), expected) {
+			t.Errorf("Expected the source to contain %s but received:\n%s", expected, src.String())
+		}
+	}
+
+	var yaml bytes.Buffer
+	if err := r.WriteYAML(&yaml); err != nil {
+		t.Fatal(err)
+	}
+	tests, err := ReadFileTests(writeTemp(t, "recorded.yaml", yaml.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 2 || tests[1].Method != "POST" || len(tests[1].Contains) != 1 {
+		t.Errorf("Expected the file tests to round trip but received:\n%s", yaml.String())
+	}
+}
+
+func TestRecordingProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		rw.Header().Set("Set-Cookie", "session=abc")
+		rw.WriteHeader(http.StatusCreated)
+		io.WriteString(rw, `{"token":"abc","echo":`+string(body)+`}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewRecordingProxy(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(p)
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("POST", proxy.URL+"/sessions", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusCreated || !strings.Contains(string(body), "hunter2") {
+		t.Fatalf("Expected the proxy to forward the exchange unchanged but received %d %s", rsp.StatusCode, body)
+	}
+
+	exchanges := p.Exchanges()
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange but received %d", len(exchanges))
+	}
+	x := exchanges[0]
+	recorded := string(JSON(x.RequestHeaders)) + string(JSON(x.ResponseHeaders)) + string(x.RequestBody) + string(x.ResponseBody)
+	for _, secret := range []string{"Bearer secret", "hunter2", "session=abc", `"abc"`} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("Expected %s to be redacted but received %s", secret, recorded)
+		}
+	}
+}
+
+// writeTemp writes the file to a temporary directory and returns its path.
+func writeTemp(t *testing.T, name string, b []byte) string {
+	t.Helper()
+
+	path := t.TempDir() + "/" + name
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
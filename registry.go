@@ -0,0 +1,74 @@
+package truth
+
+import (
+	"sort"
+	"sync"
+)
+
+type (
+	// Entry pairs a registered Definition with the test cases which exercise it.
+	Entry struct {
+		Definition Definition
+		Cases      TestCases
+	}
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Entry{}
+)
+
+// Register records the Definition and its test cases so harnesses which operate on
+// every endpoint of an API can find them. Registering the same method and path again
+// adds the test cases to the existing entry. RunIntegrationTests registers the
+// Definitions it runs automatically.
+func Register(def Definition, cases TestCases) {
+	cases.init(def, getCaller(2))
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	key := def.Method + ":" + def.Path
+
+	e, ok := registry[key]
+	if !ok {
+		e = &Entry{Definition: def}
+		registry[key] = e
+	}
+
+	for _, tc := range cases {
+		if !e.Cases.contains(tc) {
+			e.Cases = append(e.Cases, tc)
+		}
+	}
+}
+
+// Registered returns every registered entry ordered by path and method.
+func Registered() []Entry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	entries := make([]Entry, 0, len(registry))
+	for _, e := range registry {
+		entries = append(entries, Entry{Definition: e.Definition, Cases: append(TestCases(nil), e.Cases...)})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Definition, entries[j].Definition
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+
+	return entries
+}
+
+func (cases TestCases) contains(tc *TestCase) bool {
+	for _, c := range cases {
+		if c == tc {
+			return true
+		}
+	}
+	return false
+}
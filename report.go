@@ -0,0 +1,457 @@
+package truth
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The statuses of a Result.
+const (
+	ResultPassed  = "passed"
+	ResultFailed  = "failed"
+	ResultSkipped = "skipped"
+	ResultError   = "error"
+)
+
+type (
+	// Result is the outcome of a test case run by a Runner. The Package, Name and
+	// StatsKey of the Definition let dashboards group results by endpoint.
+	Result struct {
+		Package  string
+		Name     string
+		StatsKey string
+		Method   string
+		Path     string
+
+		// Case is the name of the test case.
+		Case     string
+		Status   string
+		Started  time.Time
+		Duration time.Duration
+		// Failures holds the failure messages of a failed test case or the error
+		// which stopped it from running. A failed Integration adds one message;
+		// the failures it reported are left to the test.
+		Failures []string
+	}
+
+	// Reporter receives the result of every test case run by a Runner so the
+	// results can be read by tools other than `go test`. Reporters are called from
+	// one test case at a time.
+	Reporter interface {
+		Report(r Result)
+		// Flush writes the results which are not yet written and returns the first
+		// error met while writing any of them.
+		Flush() error
+	}
+)
+
+var (
+	reportersMu sync.Mutex
+	reporters   []Reporter
+)
+
+// AddReporter sends the result of every test case run from now on to the
+// reporter. Flush the reporters once the tests have run:
+//
+//	func TestMain(m *testing.M) {
+//		f, _ := os.Create("junit.xml")
+//		truth.AddReporter(truth.NewJUnitReporter(f))
+//		code := m.Run()
+//		if err := truth.FlushReporters(); err != nil {
+//			fmt.Println(err)
+//		}
+//		f.Close()
+//		os.Exit(code)
+//	}
+func AddReporter(r Reporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+
+	reporters = append(reporters, r)
+}
+
+// FlushReporters flushes every reporter and returns the first error.
+func FlushReporters() error {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+
+	var first error
+	for _, r := range reporters {
+		if err := r.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// report sends the result to the reporters.
+func report(r Result) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+
+	for _, reporter := range reporters {
+		reporter.Report(r)
+	}
+}
+
+// newResult returns a passing Result for the test case.
+func newResult(def Definition, tc TestCase) Result {
+	return Result{
+		Package:  def.Package,
+		Name:     def.Name,
+		StatsKey: def.StatsKey,
+		Method:   def.Method,
+		Path:     def.Path,
+		Case:     tc.Name,
+		Status:   ResultPassed,
+		Started:  time.Now(),
+	}
+}
+
+// endpoint names the Definition of the result.
+func (r Result) endpoint() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Method + " " + r.Path
+}
+
+type (
+	jsonReporter struct {
+		enc *json.Encoder
+		err error
+	}
+
+	// jsonResult is a Result as it is written by the JSON Lines reporter.
+	jsonResult struct {
+		Package    string    `json:"package,omitempty"`
+		Name       string    `json:"name,omitempty"`
+		StatsKey   string    `json:"statsKey,omitempty"`
+		Method     string    `json:"method"`
+		Path       string    `json:"path"`
+		Case       string    `json:"case"`
+		Status     string    `json:"status"`
+		Started    time.Time `json:"started"`
+		DurationMS float64   `json:"durationMs"`
+		Failures   []string  `json:"failures,omitempty"`
+	}
+)
+
+// NewJSONReporter returns a Reporter writing every result to w as a line of
+// JSON as soon as the test case has run.
+func NewJSONReporter(w io.Writer) Reporter {
+	return &jsonReporter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonReporter) Report(r Result) {
+	if j.err != nil {
+		return
+	}
+	j.err = j.enc.Encode(jsonResult{
+		Package:    r.Package,
+		Name:       r.Name,
+		StatsKey:   r.StatsKey,
+		Method:     r.Method,
+		Path:       r.Path,
+		Case:       r.Case,
+		Status:     r.Status,
+		Started:    r.Started,
+		DurationMS: milliseconds(r.Duration),
+		Failures:   r.Failures,
+	})
+}
+
+func (j *jsonReporter) Flush() error {
+	return j.err
+}
+
+type (
+	tapReporter struct {
+		w   io.Writer
+		n   int
+		err error
+	}
+
+	// tapDiagnostic is the YAML block describing a test point.
+	tapDiagnostic struct {
+		Package    string   `yaml:"package,omitempty"`
+		Name       string   `yaml:"name,omitempty"`
+		StatsKey   string   `yaml:"statsKey,omitempty"`
+		Method     string   `yaml:"method"`
+		Path       string   `yaml:"path"`
+		Status     string   `yaml:"status"`
+		DurationMS float64  `yaml:"duration_ms"`
+		Failures   []string `yaml:"failures,omitempty"`
+	}
+)
+
+// NewTAPReporter returns a Reporter writing a test point to w in the TAP
+// version 13 format as soon as each test case has run. Flush writes the plan.
+func NewTAPReporter(w io.Writer) Reporter {
+	return &tapReporter{w: w}
+}
+
+func (t *tapReporter) Report(r Result) {
+	if t.err != nil {
+		return
+	}
+
+	b := &strings.Builder{}
+	if t.n == 0 {
+		b.WriteString("TAP version 13\n")
+	}
+	t.n++
+
+	ok := "ok"
+	if r.Status == ResultFailed || r.Status == ResultError {
+		ok = "not ok"
+	}
+	// A `#` would start a directive so it is escaped in descriptions.
+	description := strings.Replace(r.endpoint()+": "+r.Case, "#", `\#`, -1)
+	fmt.Fprintf(b, "%s %d - %s", ok, t.n, strings.Replace(description, "\n", " ", -1))
+	if r.Status == ResultSkipped {
+		b.WriteString(" # SKIP not selected by its tags")
+	}
+	b.WriteString("\n")
+
+	var diagnostic strings.Builder
+	enc := yaml.NewEncoder(&diagnostic)
+	enc.SetIndent(2)
+	err := enc.Encode(tapDiagnostic{
+		Package:    r.Package,
+		Name:       r.Name,
+		StatsKey:   r.StatsKey,
+		Method:     r.Method,
+		Path:       r.Path,
+		Status:     r.Status,
+		DurationMS: milliseconds(r.Duration),
+		Failures:   r.Failures,
+	})
+	if err != nil {
+		t.err = err
+		return
+	}
+	b.WriteString("  ---\n")
+	for _, l := range strings.Split(strings.TrimRight(diagnostic.String(), "\n"), "\n") {
+		fmt.Fprintf(b, "  %s\n", l)
+	}
+	b.WriteString("  ...\n")
+
+	_, t.err = io.WriteString(t.w, b.String())
+}
+
+func (t *tapReporter) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+
+	plan := fmt.Sprintf("1..%d\n", t.n)
+	if t.n == 0 {
+		plan = "TAP version 13\n" + plan
+	}
+	_, err := io.WriteString(t.w, plan)
+	return err
+}
+
+type (
+	junitReporter struct {
+		w       io.Writer
+		results []Result
+	}
+
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Errors   int          `xml:"errors,attr"`
+		Skipped  int          `xml:"skipped,attr"`
+		Time     string       `xml:"time,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+
+	junitSuite struct {
+		Name       string          `xml:"name,attr"`
+		Tests      int             `xml:"tests,attr"`
+		Failures   int             `xml:"failures,attr"`
+		Errors     int             `xml:"errors,attr"`
+		Skipped    int             `xml:"skipped,attr"`
+		Time       string          `xml:"time,attr"`
+		Timestamp  string          `xml:"timestamp,attr,omitempty"`
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitCase     `xml:"testcase"`
+	}
+
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		Classname string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitMessage `xml:"failure,omitempty"`
+		Error     *junitMessage `xml:"error,omitempty"`
+		Skipped   *junitMessage `xml:"skipped,omitempty"`
+	}
+
+	junitMessage struct {
+		Message string `xml:"message,attr,omitempty"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// NewJUnitReporter returns a Reporter writing the results to w as JUnit XML
+// when it is flushed. Each Definition is a test suite named by its Package and
+// Name, or its method and path when it has no Name, whose properties hold the
+// StatsKey.
+func NewJUnitReporter(w io.Writer) Reporter {
+	return &junitReporter{w: w}
+}
+
+func (j *junitReporter) Report(r Result) {
+	j.results = append(j.results, r)
+}
+
+func (j *junitReporter) Flush() error {
+	doc := junitSuites{}
+	index := map[string]int{}
+	var durations []time.Duration
+
+	for _, r := range j.results {
+		name := r.endpoint()
+		if r.Package != "" {
+			name = r.Package + "." + name
+		}
+
+		key := r.Method + ":" + r.Path + ":" + name
+		i, ok := index[key]
+		if !ok {
+			i = len(doc.Suites)
+			index[key] = i
+			durations = append(durations, 0)
+			doc.Suites = append(doc.Suites, junitSuite{
+				Name:      name,
+				Timestamp: r.Started.UTC().Format("2006-01-02T15:04:05"),
+				Properties: []junitProperty{
+					{Name: "package", Value: r.Package},
+					{Name: "name", Value: r.Name},
+					{Name: "statsKey", Value: r.StatsKey},
+					{Name: "method", Value: r.Method},
+					{Name: "path", Value: r.Path},
+				},
+			})
+		}
+		s := &doc.Suites[i]
+
+		c := junitCase{Name: r.Case, Classname: name, Time: seconds(r.Duration)}
+		details := strings.Join(r.Failures, "\n")
+		switch r.Status {
+		case ResultFailed:
+			c.Failure = &junitMessage{Message: firstLine(details), Text: details}
+			s.Failures++
+		case ResultError:
+			c.Error = &junitMessage{Message: firstLine(details), Text: details}
+			s.Errors++
+		case ResultSkipped:
+			c.Skipped = &junitMessage{Message: "Not selected by its tags"}
+			s.Skipped++
+		}
+
+		s.Tests++
+		s.Cases = append(s.Cases, c)
+		durations[i] += r.Duration
+	}
+
+	var total time.Duration
+	for i := range doc.Suites {
+		doc.Suites[i].Time = seconds(durations[i])
+		total += durations[i]
+	}
+
+	sort.SliceStable(doc.Suites, func(a, b int) bool { return doc.Suites[a].Name < doc.Suites[b].Name })
+	for _, s := range doc.Suites {
+		doc.Tests += s.Tests
+		doc.Failures += s.Failures
+		doc.Errors += s.Errors
+		doc.Skipped += s.Skipped
+	}
+	doc.Time = seconds(total)
+
+	if _, err := io.WriteString(j.w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(j.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n")
+
+	j.results = nil
+	return err
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// recorder is the testing.T of a Runner. It records the failures the Runner
+// reports in the Result of the test case.
+type recorder struct {
+	*testing.T
+	result Result
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.Helper()
+	msg := fmt.Sprintf(format, args...)
+	r.result.Failures = append(r.result.Failures, msg)
+	r.T.Error(msg)
+}
+
+// Error fails a test case which cannot run. Unlike T.Fatal it does not stop the
+// test so the test cases sharing it still run and are reported.
+func (r *recorder) Error(err error) {
+	r.Helper()
+	r.result.Status = ResultError
+	r.result.Failures = append(r.result.Failures, err.Error())
+	r.T.Error(err)
+}
+
+// finish reports the result of the test case. A test case which returned an
+// error could not run.
+func (r *recorder) finish(err error) {
+	r.result.Duration = time.Since(r.result.Started)
+
+	switch {
+	case r.result.Status == ResultError:
+	case err != nil:
+		r.result.Status = ResultError
+		r.result.Failures = append(r.result.Failures, err.Error())
+	case r.result.Status == ResultPassed && len(r.result.Failures) > 0:
+		r.result.Status = ResultFailed
+	}
+
+	report(r.result)
+}
@@ -0,0 +1,245 @@
+package truth
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resultsReporter keeps the results it receives.
+type resultsReporter struct {
+	results []Result
+}
+
+func (r *resultsReporter) Report(result Result) {
+	r.results = append(r.results, result)
+}
+
+func (r *resultsReporter) Flush() error {
+	return nil
+}
+
+func TestRunnerReportsSharedTest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	def := Definition{Method: "GET", Path: "/items", MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON}
+	failIntegration := func(i Integration) { i.Error("Integration failed") }
+	passIntegration := func(i Integration) {}
+
+	tests := []struct {
+		name     string
+		tags     string
+		cases    TestCases
+		expected []string
+	}{
+		{
+			name:     "passed",
+			cases:    TestCases{{Name: "found", Path: "/items", Integration: passIntegration}},
+			expected: []string{ResultPassed},
+		},
+		{
+			name: "failure before an integration",
+			cases: TestCases{
+				{Name: "missing", Path: "/missing"},
+				{Name: "integration", Path: "/items", Integration: failIntegration},
+				{Name: "found", Path: "/items"},
+			},
+			expected: []string{ResultFailed, ResultFailed, ResultPassed},
+		},
+		{
+			name: "cases after an undecodable result",
+			cases: TestCases{
+				{Name: "result", Path: "/items", Result: &[]string{}},
+				{Name: "found", Path: "/items"},
+			},
+			expected: []string{ResultFailed, ResultPassed},
+		},
+		{
+			name:     "skipped",
+			tags:     "!slow",
+			cases:    TestCases{{Name: "smoke", Path: "/items", Tags: []string{"slow"}}},
+			expected: []string{ResultSkipped},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func(tags string) { tagsFlag = tags }(tagsFlag)
+			tagsFlag = test.tags
+
+			rep := &resultsReporter{}
+			withReporter(t, rep)
+
+			// The test cases share a test which fails so it is run apart from t.
+			runShared(func(t *testing.T) {
+				for _, tc := range test.cases {
+					tc.alias = tc.Name
+					NewRunner(NewClient(srv.URL))(t, def, *tc)
+				}
+			})
+
+			var actual []string
+			for _, r := range rep.results {
+				actual = append(actual, r.Status)
+			}
+			if string(JSON(actual)) != string(JSON(test.expected)) {
+				t.Errorf("Expected %v but received %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReporters(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := []Result{
+		{Package: "users", Name: "List users", StatsKey: "users.list", Method: "GET", Path: "/users", Case: "all", Status: ResultPassed, Started: started, Duration: 1500 * time.Millisecond},
+		{Package: "users", Name: "List users", StatsKey: "users.list", Method: "GET", Path: "/users", Case: "page #2", Status: ResultFailed, Started: started, Duration: 500 * time.Millisecond, Failures: []string{"Expected statuscode 200 but received 500", "Second"}},
+		{Method: "POST", Path: "/sessions", Case: "login", Status: ResultError, Started: started, Failures: []string{"No mux"}},
+		{Method: "POST", Path: "/sessions", Case: "smoke", Status: ResultSkipped, Started: started},
+	}
+
+	tests := []struct {
+		name     string
+		reporter func(b *bytes.Buffer) Reporter
+		check    func(t *testing.T, out string)
+	}{
+		{
+			name:     "json",
+			reporter: func(b *bytes.Buffer) Reporter { return NewJSONReporter(b) },
+			check: func(t *testing.T, out string) {
+				lines := strings.Split(strings.TrimSpace(out), "\n")
+				if len(lines) != len(results) {
+					t.Fatalf("Expected %d lines but received:\n%s", len(results), out)
+				}
+				var r jsonResult
+				if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+					t.Fatal(err)
+				}
+				if r.StatsKey != "users.list" || r.Status != ResultFailed || r.DurationMS != 500 || len(r.Failures) != 2 {
+					t.Errorf("Unexpected result %s", lines[1])
+				}
+			},
+		},
+		{
+			name:     "tap",
+			reporter: func(b *bytes.Buffer) Reporter { return NewTAPReporter(b) },
+			check: func(t *testing.T, out string) {
+				for _, expected := range []string{
+					"TAP version 13\nok 1 - List users: all\n",
+					"not ok 2 - List users: page \\#2\n",
+					"not ok 3 - POST /sessions: login\n",
+					"ok 4 - POST /sessions: smoke # SKIP not selected by its tags\n",
+					"    - Expected statuscode 200 but received 500\n",
+				} {
+					if !strings.Contains(out, expected) {
+						t.Errorf("Expected %q within:\n%s", expected, out)
+					}
+				}
+				if !strings.HasSuffix(out, "1..4\n") {
+					t.Errorf("Expected the plan to end the output but received:\n%s", out)
+				}
+			},
+		},
+		{
+			name:     "junit",
+			reporter: func(b *bytes.Buffer) Reporter { return NewJUnitReporter(b) },
+			check: func(t *testing.T, out string) {
+				var doc junitSuites
+				if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatal(err)
+				}
+				if doc.Tests != 4 || doc.Failures != 1 || doc.Errors != 1 || doc.Skipped != 1 || doc.Time != "2.000" {
+					t.Errorf("Unexpected totals in:\n%s", out)
+				}
+				if len(doc.Suites) != 2 || doc.Suites[0].Name != "POST /sessions" || doc.Suites[1].Name != "users.List users" {
+					t.Fatalf("Expected a suite for each Definition sorted by name but received:\n%s", out)
+				}
+				failed := doc.Suites[1].Cases[1]
+				if failed.Failure == nil || failed.Failure.Message != "Expected statuscode 200 but received 500" || failed.Failure.Text != strings.Join(results[1].Failures, "\n") {
+					t.Errorf("Unexpected failure in:\n%s", out)
+				}
+				if doc.Suites[1].Properties[2] != (junitProperty{Name: "statsKey", Value: "users.list"}) {
+					t.Errorf("Expected the StatsKey property but received %v", doc.Suites[1].Properties)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			r := test.reporter(&b)
+			for _, result := range results {
+				r.Report(result)
+			}
+			if err := r.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, b.String())
+		})
+	}
+}
+
+func TestRecorderFinish(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		failures []string
+		err      error
+		expected string
+	}{
+		{name: "passed", status: ResultPassed, expected: ResultPassed},
+		{name: "failures", status: ResultPassed, failures: []string{"Expected statuscode 200 but received 500"}, expected: ResultFailed},
+		{name: "error returned", status: ResultPassed, err: errors.New("Preflight failed"), expected: ResultError},
+		{name: "error recorded", status: ResultError, failures: []string{errNoMux.Error()}, err: errors.New("ignored"), expected: ResultError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rep := &resultsReporter{}
+			withReporter(t, rep)
+
+			rec := &recorder{T: t, result: Result{Status: test.status, Failures: test.failures, Started: time.Now()}}
+			rec.finish(test.err)
+
+			if len(rep.results) != 1 || rep.results[0].Status != test.expected {
+				t.Errorf("Expected one %s result but received %s", test.expected, JSON(rep.results))
+			}
+		})
+	}
+}
+
+// withReporter sends the results reported during the test to the reporter
+// alone.
+func withReporter(t *testing.T, r Reporter) {
+	reportersMu.Lock()
+	registered := reporters
+	reporters = []Reporter{r}
+	reportersMu.Unlock()
+
+	t.Cleanup(func() {
+		reportersMu.Lock()
+		reporters = registered
+		reportersMu.Unlock()
+	})
+}
+
+// runShared runs the test on a testing.T of its own so its failures do not fail
+// the test calling it. It reports if the test passed.
+func runShared(test func(t *testing.T)) bool {
+	match := func(pat, str string) (bool, error) { return true, nil }
+	return testing.RunTests(match, []testing.InternalTest{{Name: "Shared", F: test}})
+}
@@ -3,6 +3,7 @@ package truth
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 type (
@@ -19,15 +21,20 @@ type (
 
 // RunIntegrationTests runs integration or full-stack tests using the provided metadata and test cases.
 // Provide a client to perform full-stack. If nil is provided the server's Mux will be called directly.
+// Each test case runs in a subtest named after it.
 func RunIntegrationTests(t *testing.T, def Definition, cases TestCases, c *Client) error {
 
 	cases.init(def, getCaller(2))
+	Register(def, cases)
 
 	for _, tc := range cases {
-		if err := NewRunner(c)(t, def, *tc); err != nil {
-			t.Error(err)
-			t.FailNow()
-
+		var err error
+		t.Run(tc.Name, func(t *testing.T) {
+			if err = NewRunner(c)(t, def, *tc); err != nil {
+				t.Error(err)
+			}
+		})
+		if err != nil {
 			fmt.Printf("%s: Error running integration tests: %s\n", tc.Name, err)
 			return err
 		}
@@ -61,14 +68,27 @@ func ToggleVerbose() {
 }
 
 // NewRunner builds a function to test an API endpoint. Provide a client to
-// perform a full-stack call to a webserver. Without a client the server MUX
-// will be called directly to perform the test in-process.
+// perform a full-stack call to a webserver. Without a client the mode of the
+// package decides how the server MUX is called, in-process by default. See
+// SetMode.
+//
+// A test case which is not selected by the `-truth.tags` flag skips the test so
+// run each test case in its own subtest as RunIntegrationTests does.
 func NewRunner(c *Client) Runner {
-	return func(t *testing.T, def Definition, tc TestCase) error {
+	return func(t *testing.T, def Definition, tc TestCase) (err error) {
+		rec := &recorder{T: t, result: newResult(def, tc)}
+
+		if !selected(tc) {
+			rec.result.Status = ResultSkipped
+			report(rec.result)
+			t.Skipf("%s is not selected by its tags", tc.alias)
+		}
 
-		print := (verbose || tc.Verbose)
+		// Every failure is reported through the recorder so the result of the
+		// test case carries it.
+		defer func() { rec.finish(err) }()
 
-		if printTestRuns {
+		if printTestRuns || verbose {
 			fmt.Printf("Running %#v\n", tc.alias)
 		}
 
@@ -77,46 +97,38 @@ func NewRunner(c *Client) Runner {
 			return fmt.Errorf("%s: Preflight failed: %s", tc.alias, err.Error())
 		}
 
-		// Prepare to execute the request.
-		RR := httptest.NewRecorder()
-		var body []byte
-
-		// If we have a client we're going to perform a full HTTP test.
-		if c != nil {
-			var err error
-			var rsp *http.Response
-			rsp, body, err = c.MakeRequest(def, tc, nil)
-			if err != nil {
-				return fmt.Errorf("%s: Unable to make HTTP request: %s", tc.alias, err.Error())
-			}
-			// Copy the response into recorder
-			RR.Code = rsp.StatusCode
-			RR.Body = bytes.NewBuffer(body)
-			for k, v := range rsp.Header {
-				RR.HeaderMap[k] = v
-			}
-		} else {
-			if muxUnderTest == nil {
-				t.Fatalf("Unable to execute test. You must first call `truth.SetMux(http.Handler)` to provide truth with a server to test.")
+		client := c
+		if client == nil {
+			if client, err = modeClient(); err == errNoMux {
+				rec.Error(err)
+				return nil
+			} else if err != nil {
+				return err
 			}
+		}
 
-			var err error
-			var req *http.Request
+		req, RR, body, err := send(client, def, tc)
+		if err == errNoMux {
+			rec.Error(err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-			req, err = integrationClient.BuildRequest(def, tc)
+		// In parity mode the response over the wire must match the response
+		// in-process.
+		if c == nil && Mode() == ModeParity {
+			inRR, inBody, err := serveInProcess(def, tc)
+			if err == errNoMux {
+				rec.Error(err)
+				return nil
+			}
 			if err != nil {
 				return err
 			}
-
-			if print {
-				fmt.Printf("Calling the server mix call to `%s:%s`\n", req.Method, req.RequestURI)
-			}
-
-			muxUnderTest.ServeHTTP(RR, req)
-
-			body, err = ioutil.ReadAll(RR.Body)
-			if err != nil {
-				return fmt.Errorf("%s: Unable to read response from Response Recorder: %s", tc.alias, err.Error())
+			for _, d := range compareResponses(inRR, inBody, RR, body, comparison{ignore: parityIgnore()}) {
+				rec.Errorf("%s: In-process (A) and over the wire (B) responses differ at `%s:%s`: %s%s", tc.alias, def.Method, tc.Path, d, reproduce(req, client))
 			}
 		}
 
@@ -126,7 +138,7 @@ func NewRunner(c *Client) Runner {
 		}
 
 		if RR.Code != tc.Status {
-			t.Errorf("%s: Expected statuscode %d but received %d at `%s:%s`", tc.alias, tc.Status, RR.Code, def.Method, tc.Path)
+			rec.Errorf("%s: Expected statuscode %d but received %d at `%s:%s`%s", tc.alias, tc.Status, RR.Code, def.Method, tc.Path, reproduce(req, client))
 			return nil
 		}
 
@@ -134,12 +146,12 @@ func NewRunner(c *Client) Runner {
 		// If so, we won't bother with any deeper testing of the body than this exact match check.
 		if tc.ExpectBody != nil {
 			if len(body) == 0 {
-				t.Errorf("%s: Empty response body when a error response was expected", tc.alias)
+				rec.Errorf("%s: Empty response body when a error response was expected%s", tc.alias, reproduce(req, client))
 				return nil
 			}
 
 			if actual, expected := strings.TrimSpace(string(body)), strings.TrimSpace(string(tc.ExpectBody)); actual != expected {
-				t.Fatalf("%s: Response was not an exact match:\nExpected: `%s`\nReceived: `%s`", tc.alias, expected, actual)
+				rec.Fatalf("%s: Response was not an exact match:\nExpected: `%s`\nReceived: `%s`%s", tc.alias, expected, actual, reproduce(req, client))
 			}
 
 			return nil
@@ -151,7 +163,7 @@ func NewRunner(c *Client) Runner {
 			content := string(body)
 			for i, q := range tc.Contains {
 				if !strings.Contains(content, q) {
-					t.Errorf("%s: Response body did not contain search term #%d %#v", tc.alias, i, q)
+					rec.Errorf("%s: Response body did not contain search term #%d %#v%s", tc.alias, i, q, reproduce(req, client))
 				}
 			}
 		}
@@ -159,26 +171,152 @@ func NewRunner(c *Client) Runner {
 		if tc.Result != nil {
 			// TODO Use the decoders
 			if err := json.Unmarshal(body, &tc.Result); err != nil {
-				t.Fatalf("%s: Unable to decode response into Result %#T", tc.Result)
+				rec.Errorf("%s: Unable to decode response into Result %T%s", tc.alias, tc.Result, reproduce(req, client))
 				tc.Result = nil
 				return nil
 			}
 		}
 
+		// The Integration runs in a subtest so its failures are known even when
+		// the test had failed before it ran.
 		if tc.Integration != nil {
-			tc.Integration(Integration{
-				T:      t,
-				TC:     tc,
-				Body:   body,
-				RR:     RR,
-				Client: c,
+			ok := t.Run("Integration", func(t *testing.T) {
+				tc.Integration(Integration{
+					T:      t,
+					TC:     tc,
+					Body:   body,
+					RR:     RR,
+					Client: client,
+				})
 			})
+			if !ok {
+				rec.result.Failures = append(rec.result.Failures, fmt.Sprintf("%s: Integration failed at `%s:%s`", tc.alias, def.Method, tc.Path))
+			}
 		}
 
 		return nil
 	}
 }
 
+// checkResponse returns the problems with a response which must have the status,
+// match the expected body, unless it is nil, and contain every search term. It
+// checks responses to contracts and file tests verified outside of go test. An
+// expected JSON body matches any document holding the same value because the
+// files store bodies re-encoded, so key order and whitespace do not matter. The
+// body is not checked when the status differs.
+func checkResponse(status int, expected []byte, contains []string, code int, body []byte) []string {
+	if code != status {
+		return []string{fmt.Sprintf("Expected statuscode %d but received %d", status, code)}
+	}
+
+	var problems []string
+
+	switch {
+	case expected == nil:
+	case len(body) == 0:
+		problems = append(problems, fmt.Sprintf("Empty response body when `%s` was expected", strings.TrimSpace(string(expected))))
+	case !jsonEqual(expected, body):
+		problems = append(problems, fmt.Sprintf("Response body did not match:\nExpected: `%s`\nReceived: `%s`", strings.TrimSpace(string(expected)), strings.TrimSpace(string(body))))
+	}
+
+	for n, q := range contains {
+		if !strings.Contains(string(body), q) {
+			problems = append(problems, fmt.Sprintf("Response body did not contain search term #%d %#v", n, q))
+		}
+	}
+
+	return problems
+}
+
+// errNoMux is returned when a test is run in-process before a mux was provided.
+var errNoMux = errors.New("Unable to execute test. You must first call `truth.SetMux(http.Handler)` to provide truth with a server to test.")
+
+// exchange performs the request described by the test case and returns the recorded
+// response along with its body. Provide a client to perform a full-stack call. Without
+// a client the server MUX will be called directly.
+func exchange(c *Client, def Definition, tc TestCase) (*httptest.ResponseRecorder, []byte, error) {
+	_, RR, body, err := send(c, def, tc)
+	return RR, body, err
+}
+
+// send performs the request described by the test case as exchange does and also
+// returns the request which was sent so failures can show how to reproduce it.
+func send(c *Client, def Definition, tc TestCase) (*http.Request, *httptest.ResponseRecorder, []byte, error) {
+	// Without a client the mode of the package decides how the mux is reached.
+	if c == nil {
+		var err error
+		if c, err = modeClient(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// If we have a client we're going to perform a full HTTP test.
+	if c != nil {
+		started := time.Now()
+		rsp, body, err := c.MakeRequest(def, tc, nil)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: Unable to make HTTP request: %s", tc.alias, err.Error())
+		}
+		defer rsp.Body.Close()
+		recordExchange(c, rsp.Request, started, rsp.StatusCode, rsp.Header, body)
+
+		// Copy the response into recorder
+		RR := httptest.NewRecorder()
+		RR.Code = rsp.StatusCode
+		RR.Body = bytes.NewBuffer(body)
+		for k, v := range rsp.Header {
+			RR.HeaderMap[k] = v
+		}
+		return rsp.Request, RR, body, nil
+	}
+
+	if muxUnderTest == nil {
+		return nil, nil, nil, errNoMux
+	}
+	return serveRequest(muxUnderTest, def, tc)
+}
+
+// serveInProcess calls the server MUX directly with the request described by the
+// test case.
+func serveInProcess(def Definition, tc TestCase) (*httptest.ResponseRecorder, []byte, error) {
+	if muxUnderTest == nil {
+		return nil, nil, errNoMux
+	}
+
+	return serveHandler(muxUnderTest, def, tc)
+}
+
+// serveHandler calls the handler directly with the request described by the test
+// case.
+func serveHandler(h http.Handler, def Definition, tc TestCase) (*httptest.ResponseRecorder, []byte, error) {
+	_, RR, body, err := serveRequest(h, def, tc)
+	return RR, body, err
+}
+
+// serveRequest calls the handler as serveHandler does and also returns the request.
+func serveRequest(h http.Handler, def Definition, tc TestCase) (*http.Request, *httptest.ResponseRecorder, []byte, error) {
+	req, err := integrationClient.BuildRequest(def, tc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if verbose || tc.Verbose {
+		fmt.Printf("Calling the server mix call to `%s:%s`\n", req.Method, req.URL.RequestURI())
+	}
+
+	started := time.Now()
+	RR := httptest.NewRecorder()
+	h.ServeHTTP(RR, req)
+
+	body, err := ioutil.ReadAll(RR.Body)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: Unable to read response from Response Recorder: %s", tc.alias, err.Error())
+	}
+	recordExchange(nil, req, started, RR.Code, RR.Header(), body)
+
+	return req, RR, body, nil
+}
+
 func preflight(def Definition, path string) error {
 	switch def.Method {
 	case http.MethodPost, http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead,
@@ -0,0 +1,40 @@
+package truth
+
+import (
+	"testing"
+)
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected string
+		contains []string
+		code     int
+		body     string
+		problems int
+	}{
+		{name: "status", status: 200, code: 200, body: `{}`},
+		{name: "wrong status skips the body", status: 201, expected: `{"id":1}`, contains: []string{"id"}, code: 500, body: `oops`, problems: 1},
+		{name: "exact JSON ignores key order", status: 200, expected: `{"a":1,"b":2}`, code: 200, body: "{\"b\": 2, \"a\": 1}\n"},
+		{name: "exact text", status: 200, expected: "ok", code: 200, body: "ok\n"},
+		{name: "mismatch", status: 200, expected: `{"a":1}`, code: 200, body: `{"a":2}`, problems: 1},
+		{name: "empty body", status: 200, expected: `{"a":1}`, code: 200, problems: 1},
+		{name: "contains", status: 200, contains: []string{"Ann", "Bob"}, code: 200, body: `["Ann"]`, problems: 1},
+		{name: "mismatch and contains", status: 200, expected: `{"a":1}`, contains: []string{"Bob"}, code: 200, body: `{"a":2}`, problems: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expected []byte
+			if test.expected != "" {
+				expected = []byte(test.expected)
+			}
+
+			problems := checkResponse(test.status, expected, test.contains, test.code, []byte(test.body))
+			if len(problems) != test.problems {
+				t.Errorf("Expected %d problems but received %q", test.problems, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package truth
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type (
+	// Schema describes the shape of a body, or of parameters, reflected from the Go
+	// value of a Definition. It is a subset of JSON Schema so other tooling can
+	// consume it.
+	Schema struct {
+		Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+		Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+		Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	}
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bytesType      = reflect.TypeOf([]byte{})
+)
+
+// SchemaOf reflects the schema of the value. Struct fields are named by their
+// `json` tags and are required unless they are pointers or tagged `omitempty`.
+// A nil value has a nil schema.
+func SchemaOf(v interface{}) *Schema {
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+
+	return schemaOf(t, map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := *schemaOf(t.Elem(), seen)
+		s.Nullable = true
+		return &s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		// Recursive types are described once and referred to as plain objects.
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(s, t, seen)
+		return s
+	}
+
+	// Interfaces and anything else accept any value.
+	return &Schema{}
+}
+
+// addFields adds the exported fields of the struct to the schema. Embedded
+// structs without a `json` name are flattened as encoding/json does.
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, opts := f.Name, ""
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if parts := strings.SplitN(tag, ",", 2); parts[0] != "" {
+				name = parts[0]
+				if len(parts) > 1 {
+					opts = parts[1]
+				}
+			} else if len(parts) > 1 {
+				opts = parts[1]
+			}
+		}
+
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				// A struct embedding itself adds its fields once.
+				if !seen[et] {
+					seen[et] = true
+					addFields(s, et, seen)
+					delete(seen, et)
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		s.Properties[name] = schemaOf(f.Type, seen)
+
+		if f.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// requires reports if the property is required.
+func (s *Schema) requires(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Example returns a value satisfying the schema, shaped as Example shapes Go
+// values, so it can be serialized as an example of a body.
+func (s *Schema) Example() interface{} {
+	return s.example(0)
+}
+
+func (s *Schema) example(depth int) interface{} {
+	if s == nil || depth > 8 {
+		return nil
+	}
+
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "2016-04-01T12:00:00Z"
+		}
+		return "string"
+	case "boolean":
+		return true
+	case "integer", "number":
+		return 1
+	case "array":
+		return []interface{}{s.Items.example(depth + 1)}
+	case "object":
+		out := map[string]interface{}{}
+		for k, p := range s.Properties {
+			out[k] = p.example(depth + 1)
+		}
+		if s.AdditionalProperties != nil {
+			out["key"] = s.AdditionalProperties.example(depth + 1)
+		}
+		return out
+	}
+
+	return nil
+}
+
+// Validate reports if the JSON document does not satisfy the schema. Objects
+// describing their properties reject properties they do not describe, as
+// BodyDefinition.Validate does for Go values.
+func (s *Schema) Validate(body []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("Body is not valid JSON: %s", err)
+	}
+
+	return s.validate("body", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	if s == nil || s.Type == "" {
+		return nil
+	}
+
+	if v == nil {
+		if s.Nullable || s.Type == "array" || s.Type == "object" {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+
+	switch s.Type {
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		for i, item := range items {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, r := range s.Required {
+			if _, ok := obj[r]; !ok {
+				return fmt.Errorf("%s.%s is required", path, r)
+			}
+		}
+		for k, pv := range obj {
+			p, ok := s.Properties[k]
+			switch {
+			case ok:
+			case s.AdditionalProperties != nil:
+				p = s.AdditionalProperties
+			case s.Properties != nil:
+				return fmt.Errorf("%s has unknown field %#v", path, k)
+			default:
+				continue
+			}
+			if err := p.validate(path+"."+k, pv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
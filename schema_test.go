@@ -0,0 +1,96 @@
+package truth
+
+import (
+	"testing"
+)
+
+type (
+	schemaUser struct {
+		ID      int64    `json:"id"`
+		Name    string   `json:"name"`
+		Email   *string  `json:"email"`
+		Tags    []string `json:"tags,omitempty"`
+		Ignored string   `json:"-"`
+		secret  string
+		schemaAudit
+	}
+
+	schemaAudit struct {
+		Created string `json:"created"`
+	}
+
+	schemaNode struct {
+		Name     string       `json:"name"`
+		Children []schemaNode `json:"children,omitempty"`
+	}
+
+	schemaSelf struct {
+		*schemaSelf
+		Name string `json:"name"`
+	}
+)
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: `null`},
+		{name: "string", value: "", expected: `{"type":"string"}`},
+		{name: "slice", value: []int{}, expected: `{"type":"array","items":{"type":"integer","format":"int64"}}`},
+		{name: "map", value: map[string]bool{}, expected: `{"type":"object","additionalProperties":{"type":"boolean"}}`},
+		{
+			name:  "struct",
+			value: schemaUser{},
+			expected: `{"type":"object","properties":{"created":{"type":"string"},"email":{"type":"string","nullable":true},` +
+				`"id":{"type":"integer","format":"int64"},"name":{"type":"string"},"tags":{"type":"array","items":{"type":"string"}}},` +
+				`"required":["id","name","created"]}`,
+		},
+		{
+			name:     "recursive",
+			value:    schemaNode{},
+			expected: `{"type":"object","properties":{"children":{"type":"array","items":{"type":"object"}},"name":{"type":"string"}},"required":["name"]}`,
+		},
+		{
+			name:     "embeds itself",
+			value:    schemaSelf{},
+			expected: `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := string(JSON(SchemaOf(test.value))); actual != test.expected {
+				t.Errorf("Expected %s but received %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	s := SchemaOf(schemaUser{})
+
+	tests := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{name: "valid", body: `{"id": 1, "name": "Ann", "email": null, "created": "today"}`, ok: true},
+		{name: "missing required", body: `{"id": 1, "created": "today"}`},
+		{name: "wrong type", body: `{"id": "1", "name": "Ann", "created": "today"}`},
+		{name: "not json", body: `<html>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.Validate([]byte(test.body))
+			if test.ok && err != nil {
+				t.Errorf("Expected the body to be valid but received %s", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("Expected the body to be invalid")
+			}
+		})
+	}
+}
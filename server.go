@@ -0,0 +1,332 @@
+package truth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultReadyTimeout is how long a Server has to answer its readiness probe.
+	DefaultReadyTimeout = 30 * time.Second
+	// DefaultShutdownTimeout is how long a Server has to exit after it is
+	// interrupted before it is killed.
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+type (
+	// Server builds a main package and runs it as a subprocess on a free port so
+	// full-stack tests are self-contained within go test. The port is provided to
+	// the server by the PORT environment variable, and by `{port}` and `{addr}`
+	// placeholders within Args, such as `-listen={addr}`.
+	Server struct {
+		// Package is the main package to build such as `./cmd/api` or
+		// `github.com/you/service/cmd/api`.
+		Package string
+		// Dir is the directory the package is built and run in. The default is the
+		// working directory of the test.
+		Dir  string
+		Args []string
+		// Env is added to the environment of the server as `KEY=value` pairs. A
+		// PORT within Env is used instead of a free port.
+		Env []string
+
+		// Ready is the path probed until the server answers without a 5XX status
+		// code. The default is `/`.
+		Ready           string
+		ReadyTimeout    time.Duration
+		ShutdownTimeout time.Duration
+
+		// Logf receives every line the server writes to stdout and stderr. The
+		// default writes to stderr.
+		Logf func(format string, args ...interface{})
+
+		// URL is the base URL of the server once it is started.
+		URL string
+
+		cmd  *exec.Cmd
+		dir  string
+		done chan error
+		mu   sync.Mutex
+	}
+)
+
+// StartServer builds and starts the main package and returns a Client pointed
+// at it. The server is stopped and its output logged to the test when the test
+// and its subtests complete.
+//
+//	func TestUsers(t *testing.T) {
+//		c := truth.StartServer(t, &truth.Server{Package: "../advanced", Ready: "/users"})
+//		truth.RunIntegrationTests(t, getUsersDef, getUsersCases, c)
+//	}
+//
+// To share a server across a package start it from TestMain instead:
+//
+//	func TestMain(m *testing.M) {
+//		s := &truth.Server{Package: "."}
+//		if err := s.Start(); err != nil {
+//			log.Fatal(err)
+//		}
+//		code := m.Run()
+//		s.Stop()
+//		os.Exit(code)
+//	}
+func StartServer(t testing.TB, s *Server) *Client {
+	t.Helper()
+
+	if s.Logf == nil {
+		s.Logf = t.Logf
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return NewClient(s.URL)
+}
+
+// Start builds the package, starts it and waits until it is ready.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil {
+		return fmt.Errorf("Server %s is already started", s.Package)
+	}
+
+	if s.Logf == nil {
+		s.Logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "truth-server")
+	if err != nil {
+		return err
+	}
+	s.dir = dir
+
+	bin := filepath.Join(dir, "server")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	build := exec.Command("go", "build", "-o", bin, s.Package)
+	build.Dir = s.Dir
+	if out, err := build.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("Unable to build %s: %s\n%s", s.Package, err, out)
+	}
+
+	port, ok := lookupEnv(s.Env, "PORT")
+	if !ok {
+		free, err := freePort()
+		if err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		port = strconv.Itoa(free)
+	}
+	addr := "127.0.0.1:" + port
+	s.URL = "http://" + addr
+
+	args := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		a = strings.Replace(a, "{addr}", addr, -1)
+		args[i] = strings.Replace(a, "{port}", port, -1)
+	}
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(append(os.Environ(), s.Env...), "PORT="+port)
+
+	name := filepath.Base(s.Package)
+	if abs, err := filepath.Abs(filepath.Join(s.Dir, s.Package)); err == nil {
+		name = filepath.Base(abs)
+	}
+	cmd.Stdout = s.logWriter(name + " stdout")
+	cmd.Stderr = s.logWriter(name + " stderr")
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("Unable to start %s: %s", s.Package, err)
+	}
+
+	s.cmd = cmd
+	s.done = make(chan error, 1)
+	go func() { s.done <- cmd.Wait() }()
+
+	if err := s.waitReady(); err != nil {
+		s.stop()
+		return err
+	}
+
+	return nil
+}
+
+// waitReady probes the server until it answers, it exits or time runs out.
+func (s *Server) waitReady() error {
+	timeout := s.ReadyTimeout
+	if timeout == 0 {
+		timeout = DefaultReadyTimeout
+	}
+
+	ready := s.Ready
+	if ready == "" {
+		ready = "/"
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.done:
+			s.done <- err
+			return fmt.Errorf("Server %s exited before it was ready: %v", s.Package, err)
+		default:
+		}
+
+		rsp, err := client.Get(s.URL + ready)
+		if err == nil {
+			rsp.Body.Close()
+			if rsp.StatusCode < 500 {
+				return nil
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return fmt.Errorf("Server %s was not ready at %s within %s", s.Package, s.URL+ready, timeout)
+}
+
+// Stop interrupts the server and waits for it to exit. A server which does not
+// exit within the ShutdownTimeout is killed.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop()
+}
+
+func (s *Server) stop() error {
+	if s.cmd == nil {
+		return nil
+	}
+	defer func() {
+		s.cmd.Stdout.(*lineWriter).flush()
+		s.cmd.Stderr.(*lineWriter).flush()
+		os.RemoveAll(s.dir)
+		s.cmd = nil
+	}()
+
+	select {
+	case <-s.done:
+		// The server already exited.
+		return nil
+	default:
+	}
+
+	timeout := s.ShutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	// Interrupts are not supported on Windows so the server is killed.
+	if runtime.GOOS == "windows" || s.cmd.Process.Signal(os.Interrupt) != nil {
+		s.cmd.Process.Kill()
+		<-s.done
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(timeout):
+		s.cmd.Process.Kill()
+		<-s.done
+		return fmt.Errorf("Server %s did not exit within %s of an interrupt and was killed", s.Package, timeout)
+	}
+}
+
+// logWriter returns a writer sending each line to Logf. Lines are logged as
+// they are written so nothing is logged once the server has exited.
+func (s *Server) logWriter(prefix string) io.Writer {
+	return &lineWriter{prefix: prefix, logf: s.Logf}
+}
+
+type lineWriter struct {
+	prefix string
+	logf   func(format string, args ...interface{})
+	buf    []byte
+	mu     sync.Mutex
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logf("%s: %s", w.prefix, strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush logs a final line which did not end with a line break.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logf("%s: %s", w.prefix, w.buf)
+		w.buf = nil
+	}
+}
+
+// lookupEnv returns the last value of the key among the `KEY=value` pairs as
+// exec does.
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return env[i][len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// freePort asks the kernel for a free port.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
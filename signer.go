@@ -0,0 +1,260 @@
+package truth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the hex encoded HMAC-SHA256 signature of a request.
+	SignatureHeader = "X-Signature"
+	// SignatureTimestampHeader holds the Unix time the request was signed.
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+
+	// DefaultSignatureSkew is the clock skew tolerated by a Signer when none is
+	// configured.
+	DefaultSignatureSkew = 5 * time.Minute
+)
+
+// Signer signs and verifies requests for Definitions using AuthenticationChecksum.
+// The same Signer is used by the Client to sign requests and by the server to
+// verify them so both sides share one implementation:
+//
+//	signer, err := truth.NewSigner(key, "Content-Type")
+//	...
+//
+//	// Client side
+//	truth.SetCredentials(truth.AuthenticationChecksum, signer)
+//
+//	// Server side
+//	http.ListenAndServe(":8080", signer.Middleware(mux))
+//
+// The signature covers a canonical string built from the method, path, sorted
+// query string, the configured headers, a SHA-256 digest of the body and the
+// timestamp.
+type Signer struct {
+	// Key used to compute the HMAC.
+	Key []byte
+	// Headers lists the request headers included in the signature.
+	Headers []string
+	// Skew is the largest difference tolerated between the request timestamp and
+	// the verifier's clock. Defaults to DefaultSignatureSkew.
+	Skew time.Duration
+	// ReplayWindow is how long a verified signature is remembered so it cannot be
+	// replayed. Defaults to twice the Skew which covers every timestamp accepted.
+	ReplayWindow time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+var (
+	errSignatureMissing  = errors.New("Request is not signed")
+	errSignatureInvalid  = errors.New("Request signature is invalid")
+	errSignatureReplayed = errors.New("Request signature was already used")
+	errSignerKey         = errors.New("Signer requires a key")
+)
+
+// NewSigner returns a Signer using the key which includes the provided headers
+// in every signature. An empty key is rejected as anyone could sign requests.
+func NewSigner(key []byte, headers ...string) (*Signer, error) {
+	if len(key) == 0 {
+		return nil, errSignerKey
+	}
+
+	return &Signer{
+		Key:     key,
+		Headers: headers,
+	}, nil
+}
+
+// Authorize signs the request by setting the SignatureHeader and the
+// SignatureTimestampHeader.
+func (s *Signer) Authorize(req *http.Request) error {
+	if len(s.Key) == 0 {
+		return errSignerKey
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set(SignatureTimestampHeader, ts)
+	req.Header.Set(SignatureHeader, s.sign(req, body, ts))
+
+	return nil
+}
+
+// Verify reports if the request does not carry a valid signature, was signed
+// outside the tolerated clock skew or replays a signature already verified.
+// The request body is restored so handlers can read it.
+func (s *Signer) Verify(req *http.Request) error {
+	if len(s.Key) == 0 {
+		return errSignerKey
+	}
+
+	sig, ts := req.Header.Get(SignatureHeader), req.Header.Get(SignatureTimestampHeader)
+	if sig == "" || ts == "" {
+		return errSignatureMissing
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Request signature timestamp %#v is invalid", ts)
+	}
+
+	now := s.now()
+	if d := now.Sub(time.Unix(unix, 0)); d > s.skew() || d < -s.skew() {
+		return fmt.Errorf("Request signature timestamp is outside the allowed clock skew of %s", s.skew())
+	}
+
+	var body []byte
+	if req.Body != nil {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(s.sign(req, body, ts))) {
+		return errSignatureInvalid
+	}
+
+	return s.remember(sig, now)
+}
+
+// Middleware verifies every request before calling the next handler. Requests
+// failing verification receive a `401 Unauthorized` response.
+func (s *Signer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if err := s.Verify(req); err != nil {
+			http.Error(rw, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// CanonicalString returns the string signed for the request.
+func (s *Signer) CanonicalString(req *http.Request, body []byte, timestamp string) string {
+	var b strings.Builder
+
+	b.WriteString(req.Method + "\n")
+	b.WriteString(req.URL.EscapedPath() + "\n")
+	b.WriteString(canonicalQuery(req.URL.Query()) + "\n")
+
+	headers := make([]string, len(s.Headers))
+	for i, h := range s.Headers {
+		headers[i] = strings.ToLower(h)
+	}
+	sort.Strings(headers)
+	for _, h := range headers {
+		b.WriteString(h + ":" + strings.TrimSpace(req.Header.Get(h)) + "\n")
+	}
+
+	digest := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(digest[:]) + "\n")
+	b.WriteString(timestamp)
+
+	return b.String()
+}
+
+func (s *Signer) sign(req *http.Request, body []byte, timestamp string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	io.WriteString(mac, s.CanonicalString(req, body, timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// remember records the signature and forgets signatures older than the replay
+// window.
+func (s *Signer) remember(sig string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window := s.ReplayWindow
+	if window == 0 {
+		window = 2 * s.skew()
+	}
+
+	if s.seen == nil {
+		s.seen = map[string]time.Time{}
+	}
+
+	for k, at := range s.seen {
+		if now.Sub(at) > window {
+			delete(s.seen, k)
+		}
+	}
+
+	if _, ok := s.seen[sig]; ok {
+		return errSignatureReplayed
+	}
+	s.seen[sig] = now
+
+	return nil
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Signer) skew() time.Duration {
+	if s.Skew == 0 {
+		return DefaultSignatureSkew
+	}
+	return s.Skew
+}
+
+// canonicalQuery encodes the query sorted by key and then by value.
+func canonicalQuery(q url.Values) string {
+	for _, v := range q {
+		sort.Strings(v)
+	}
+	return q.Encode()
+}
+
+// requestBody returns a copy of the body of an outgoing request without
+// consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return body, nil
+	}
+
+	r, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,130 @@
+package truth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewSignerRejectsEmptyKey(t *testing.T) {
+	if _, err := NewSigner(nil); err == nil {
+		t.Error("Expected an error for an empty key")
+	}
+
+	s := &Signer{}
+	req, _ := http.NewRequest("GET", "http://localhost/users", nil)
+	if err := s.Authorize(req); err == nil {
+		t.Error("Expected Authorize to fail without a key")
+	}
+	if err := s.Verify(req); err == nil {
+		t.Error("Expected Verify to fail without a key")
+	}
+}
+
+func TestSignerVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name  string
+		alter func(req *http.Request)
+		ok    bool
+	}{
+		{name: "valid", alter: func(req *http.Request) {}, ok: true},
+		{name: "missing signature", alter: func(req *http.Request) { req.Header.Del(SignatureHeader) }},
+		{name: "missing timestamp", alter: func(req *http.Request) { req.Header.Del(SignatureTimestampHeader) }},
+		{name: "invalid timestamp", alter: func(req *http.Request) { req.Header.Set(SignatureTimestampHeader, "yesterday") }},
+		{name: "tampered method", alter: func(req *http.Request) { req.Method = "PUT" }},
+		{name: "tampered path", alter: func(req *http.Request) { req.URL.Path = "/users/2" }},
+		{name: "tampered query", alter: func(req *http.Request) { req.URL.RawQuery = "admin=true" }},
+		{name: "tampered signed header", alter: func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") }},
+		{name: "tampered body", alter: func(req *http.Request) {
+			req.Body = ioutil.NopCloser(bytes.NewBufferString(`{"name":"Mallory"}`))
+		}},
+		{name: "expired timestamp", alter: func(req *http.Request) {
+			resign(req, now.Add(-DefaultSignatureSkew-time.Second))
+		}},
+		{name: "future timestamp", alter: func(req *http.Request) {
+			resign(req, now.Add(DefaultSignatureSkew+time.Second))
+		}},
+		{name: "timestamp within skew", alter: func(req *http.Request) {
+			resign(req, now.Add(-time.Minute))
+		}, ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := signedRequest(t, now)
+			test.alter(req)
+
+			err := testSigner(now).Verify(req)
+			if test.ok && err != nil {
+				t.Errorf("Expected the request to verify but received %s", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("Expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestSignerVerifyRejectsReplay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := testSigner(now)
+
+	req := signedRequest(t, now)
+	replay := req.Clone(req.Context())
+	replay.Body = ioutil.NopCloser(bytes.NewBufferString(`{"name":"Ann"}`))
+
+	if err := s.Verify(req); err != nil {
+		t.Fatalf("Expected the request to verify but received %s", err)
+	}
+	if err := s.Verify(replay); err != errSignatureReplayed {
+		t.Errorf("Expected %s but received %v", errSignatureReplayed, err)
+	}
+}
+
+func TestSignerVerifyRestoresBody(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	req := signedRequest(t, now)
+
+	if err := testSigner(now).Verify(req); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != `{"name":"Ann"}` {
+		t.Errorf("Expected the body to be restored but received %q", body)
+	}
+}
+
+func testSigner(now time.Time) *Signer {
+	s, _ := NewSigner([]byte("secret"), "Content-Type")
+	s.Now = func() time.Time { return now }
+	return s
+}
+
+// signedRequest returns a request signed at the time as it is received by a
+// server.
+func signedRequest(t *testing.T, now time.Time) *http.Request {
+	req, err := http.NewRequest("POST", "http://localhost/users/1?b=2&a=1", bytes.NewBufferString(`{"name":"Ann"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", MIMETypeJSON)
+
+	if err := testSigner(now).Authorize(req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// resign signs the request again at the time.
+func resign(req *http.Request, at time.Time) {
+	body, _ := requestBody(req)
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set(SignatureTimestampHeader, ts)
+	req.Header.Set(SignatureHeader, testSigner(at).sign(req, body, ts))
+}
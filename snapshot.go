@@ -0,0 +1,521 @@
+package truth
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// SnapshotVersion is the version of the snapshot format written by WriteSnapshot.
+const SnapshotVersion = "truth-snapshot/2"
+
+type (
+	// Snapshot is a serializable record of every registered Definition. Compare two
+	// snapshots with DiffSnapshots to find changes which break API compatibility.
+	Snapshot struct {
+		Version string `json:"version"`
+		// APIVersion is the version of the API. Breaking changes are permitted when
+		// the major version changes.
+		APIVersion string     `json:"apiVersion,omitempty"`
+		Endpoints  []Endpoint `json:"endpoints"`
+		// Cases is the number of test cases registered for each endpoint, keyed
+		// by its method and path.
+		Cases map[string]int `json:"cases,omitempty"`
+	}
+
+	// Endpoint is the serializable form of a Definition. Go values describing
+	// parameters and bodies are replaced by their reflected schemas.
+	Endpoint struct {
+		Method           string            `json:"method" yaml:"method"`
+		Path             string            `json:"path" yaml:"path"`
+		Name             string            `json:"name,omitempty" yaml:"name,omitempty"`
+		Package          string            `json:"package,omitempty" yaml:"package,omitempty"`
+		Description      string            `json:"description,omitempty" yaml:"description,omitempty"`
+		StatsKey         string            `json:"statsKey,omitempty" yaml:"statsKey,omitempty"`
+		MIMETypeRequest  string            `json:"mimeTypeRequest,omitempty" yaml:"mimeTypeRequest,omitempty"`
+		MIMETypeResponse string            `json:"mimeTypeResponse,omitempty" yaml:"mimeTypeResponse,omitempty"`
+		Params           []string          `json:"params,omitempty" yaml:"params,omitempty"`
+		InputParams      *Schema           `json:"inputParams,omitempty" yaml:"inputParams,omitempty"`
+		QueryParams      *Schema           `json:"queryParams,omitempty" yaml:"queryParams,omitempty"`
+		RequestHeaders   map[string]string `json:"requestHeaders,omitempty" yaml:"requestHeaders,omitempty"`
+		ResponseHeaders  map[string]string `json:"responseHeaders,omitempty" yaml:"responseHeaders,omitempty"`
+		Authenticated    bool              `json:"authenticated,omitempty" yaml:"authenticated,omitempty"`
+		Authentication   string            `json:"authentication,omitempty" yaml:"authentication,omitempty"`
+		Access           AccessPolicy      `json:"access,omitempty" yaml:"access,omitempty"`
+		RequestBody      *Schema           `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+		ResponseBody     *Schema           `json:"responseBody,omitempty" yaml:"responseBody,omitempty"`
+	}
+
+	// Change describes a difference between two snapshots.
+	Change struct {
+		Breaking bool
+		Endpoint string
+		Message  string
+	}
+)
+
+// NewEndpoint returns the serializable form of the Definition.
+func NewEndpoint(def Definition) Endpoint {
+	return Endpoint{
+		Method:           def.Method,
+		Path:             def.Path,
+		Name:             def.Name,
+		Package:          def.Package,
+		Description:      def.Description,
+		StatsKey:         def.StatsKey,
+		MIMETypeRequest:  def.MIMETypeRequest,
+		MIMETypeResponse: def.MIMETypeResponse,
+		Params:           pathParams(def.Path),
+		InputParams:      SchemaOf(def.InputParams),
+		QueryParams:      SchemaOf(def.QueryParams),
+		RequestHeaders:   def.RequestHeaders,
+		ResponseHeaders:  def.ResponseHeaders,
+		Authenticated:    def.Authenticated,
+		Authentication:   def.Authentication,
+		Access:           def.Access,
+		RequestBody:      SchemaOf(def.RequestBody.Data),
+		ResponseBody:     SchemaOf(def.ResponseBody.Data),
+	}
+}
+
+// Definition returns a Definition described by the endpoint. The schemas take
+// the place of the Go values holding parameters and bodies.
+func (e Endpoint) Definition() Definition {
+	def := Definition{
+		Method:           e.Method,
+		Path:             e.Path,
+		Name:             e.Name,
+		Package:          e.Package,
+		Description:      e.Description,
+		StatsKey:         e.StatsKey,
+		MIMETypeRequest:  e.MIMETypeRequest,
+		MIMETypeResponse: e.MIMETypeResponse,
+		RequestHeaders:   e.RequestHeaders,
+		ResponseHeaders:  e.ResponseHeaders,
+		Authenticated:    e.Authenticated,
+		Authentication:   e.Authentication,
+		Access:           e.Access,
+	}
+
+	// Assigned only when present so a missing schema stays a nil interface.
+	if e.InputParams != nil {
+		def.InputParams = e.InputParams
+	}
+	if e.QueryParams != nil {
+		def.QueryParams = e.QueryParams
+	}
+	if e.RequestBody != nil {
+		def.RequestBody.Data = e.RequestBody
+	}
+	if e.ResponseBody != nil {
+		def.ResponseBody.Data = e.ResponseBody
+	}
+
+	return def
+}
+
+// Entries returns an Entry without test cases for every endpoint so tools such
+// as the Mock can operate on a snapshot.
+func (s Snapshot) Entries() []Entry {
+	entries := make([]Entry, len(s.Endpoints))
+	for i, e := range s.Endpoints {
+		entries[i] = Entry{Definition: e.Definition()}
+	}
+	return entries
+}
+
+// TakeSnapshot records every registered Definition.
+func TakeSnapshot(apiVersion string) Snapshot {
+	s := Snapshot{
+		Version:    SnapshotVersion,
+		APIVersion: apiVersion,
+		Cases:      map[string]int{},
+	}
+
+	for _, e := range Registered() {
+		ep := NewEndpoint(e.Definition)
+		s.Endpoints = append(s.Endpoints, ep)
+		s.Cases[ep.String()] = len(e.Cases)
+	}
+
+	return s
+}
+
+// Write serializes the snapshot as indented JSON.
+func (s Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteSnapshot writes a snapshot of every registered Definition to the file at
+// path.
+func WriteSnapshot(path, apiVersion string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return TakeSnapshot(apiVersion).Write(f)
+}
+
+// LoadSnapshot reads a snapshot file.
+func LoadSnapshot(path string) (Snapshot, error) {
+	s := Snapshot{}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, fmt.Errorf("Unable to decode snapshot %s: %s", path, err)
+	}
+
+	if s.Version != SnapshotVersion {
+		return s, fmt.Errorf("Snapshot %s has version %#v but %#v is required", path, s.Version, SnapshotVersion)
+	}
+
+	return s, nil
+}
+
+// CheckCompatibility compares a snapshot of the registered Definitions to the
+// baseline snapshot at path and fails the test for every breaking change unless
+// the major version of the API changed. Call it once every Definition has been
+// registered. Register the Definitions of the API before the tests run, such as
+// from TestMain, so the snapshot does not depend on which tests ran:
+//
+//	func TestMain(m *testing.M) {
+//		truth.Register(getUserDef, nil)
+//		...
+//		code := m.Run()
+//		...
+//	}
+//
+// When the tests are filtered with `-run` only the registered Definitions are
+// compared so endpoints of tests which did not run are not reported as removed.
+//
+// The baseline is only written, when it does not exist or the check passes with
+// changes, with the `-truth.update` flag so it can be committed with the change.
+func CheckCompatibility(t testing.TB, path, apiVersion string) {
+	current := TakeSnapshot(apiVersion)
+
+	baseline, err := LoadSnapshot(path)
+	if os.IsNotExist(err) {
+		if !updateFlag {
+			t.Fatalf("API snapshot baseline %s does not exist. Run the tests with -truth.update to write it", path)
+		}
+		t.Logf("Writing API snapshot baseline %s", path)
+		if err := WriteSnapshot(path, apiVersion); err != nil {
+			t.Fatalf("Unable to write API snapshot %s: %s", path, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := testsFiltered()
+	if filtered {
+		baseline = registeredOnly(baseline, current)
+	}
+
+	changes := DiffSnapshots(baseline, current)
+	versioned := MajorVersionChanged(baseline, current)
+
+	failed := false
+	for _, c := range changes {
+		if c.Breaking && !versioned {
+			failed = true
+			t.Errorf("Breaking API change: %s", c)
+			continue
+		}
+		t.Logf("API change: %s", c)
+	}
+
+	if failed || len(changes) == 0 {
+		return
+	}
+
+	switch {
+	case filtered:
+		t.Logf("API snapshot baseline %s is not written when the tests are filtered with -run", path)
+	case !updateFlag:
+		t.Logf("API snapshot baseline %s is out of date. Run the tests with -truth.update to write it", path)
+	default:
+		if err := WriteSnapshot(path, apiVersion); err != nil {
+			t.Fatalf("Unable to write API snapshot %s: %s", path, err)
+		}
+	}
+}
+
+// testsFiltered reports if `go test -run` selected which tests run.
+func testsFiltered() bool {
+	f := flag.Lookup("test.run")
+	return f != nil && f.Value.String() != ""
+}
+
+// registeredOnly returns the baseline without the endpoints missing from the
+// current snapshot.
+func registeredOnly(baseline, current Snapshot) Snapshot {
+	registered := map[string]bool{}
+	for _, e := range current.Endpoints {
+		registered[e.key()] = true
+	}
+
+	endpoints := baseline.Endpoints
+	baseline.Endpoints = nil
+	for _, e := range endpoints {
+		if registered[e.key()] {
+			baseline.Endpoints = append(baseline.Endpoints, e)
+		}
+	}
+	return baseline
+}
+
+// MajorVersionChanged reports if the major APIVersion differs between the
+// snapshots, which permits breaking changes.
+func MajorVersionChanged(before, after Snapshot) bool {
+	return majorVersion(before.APIVersion) != majorVersion(after.APIVersion)
+}
+
+func majorVersion(v string) string {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.Index(v, "."); i >= 0 {
+		return v[:i]
+	}
+	return v
+}
+
+// String describes the change.
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s (%s)", c.Endpoint, c.Message, kind)
+}
+
+// BreakingChanges returns the breaking changes.
+func BreakingChanges(changes []Change) []Change {
+	var out []Change
+	for _, c := range changes {
+		if c.Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// DiffSnapshots classifies the differences between the snapshot taken before a
+// change and the snapshot taken after it. Endpoints are matched by method and
+// path with route variables matched by position so renaming a variable is not
+// a change.
+//
+// Breaking changes are removed endpoints, removed response fields, newly
+// required request fields, parameters or headers, narrowed or changed types,
+// changed MIME types and tightened authentication.
+func DiffSnapshots(before, after Snapshot) []Change {
+	var changes []Change
+
+	index := map[string]Endpoint{}
+	for _, e := range after.Endpoints {
+		index[e.key()] = e
+	}
+
+	matched := map[string]bool{}
+	for _, o := range before.Endpoints {
+		n, ok := index[o.key()]
+		if !ok {
+			changes = append(changes, Change{Breaking: true, Endpoint: o.String(), Message: "Endpoint was removed"})
+			continue
+		}
+		matched[o.key()] = true
+		changes = append(changes, diffEndpoint(o, n)...)
+	}
+
+	for _, n := range after.Endpoints {
+		if !matched[n.key()] {
+			changes = append(changes, Change{Endpoint: n.String(), Message: "Endpoint was added"})
+		}
+	}
+
+	return changes
+}
+
+// key identifies the endpoint by method and path with route variables replaced
+// by a placeholder.
+func (e Endpoint) key() string {
+	return e.Method + " " + fillPath(e.Path, func(string) string { return "{}" })
+}
+
+// String returns the method and path of the endpoint.
+func (e Endpoint) String() string {
+	return e.Method + " " + e.Path
+}
+
+func diffEndpoint(o, n Endpoint) []Change {
+	var changes []Change
+
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, Change{Breaking: breaking, Endpoint: n.String(), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if o.MIMETypeRequest != n.MIMETypeRequest {
+		add(true, "Request MIME type changed from %#v to %#v", o.MIMETypeRequest, n.MIMETypeRequest)
+	}
+	if o.MIMETypeResponse != n.MIMETypeResponse {
+		add(true, "Response MIME type changed from %#v to %#v", o.MIMETypeResponse, n.MIMETypeResponse)
+	}
+
+	oldAuth, newAuth := o.requiresAuth(), n.requiresAuth()
+	switch {
+	case !oldAuth && newAuth:
+		add(true, "Authentication is now required")
+	case oldAuth && !newAuth:
+		add(false, "Authentication is no longer required")
+	case oldAuth && o.Authentication != n.Authentication:
+		add(true, "Authentication changed from %#v to %#v", o.Authentication, n.Authentication)
+	}
+
+	for k := range n.RequestHeaders {
+		if _, ok := o.RequestHeaders[k]; !ok {
+			add(true, "Request header %#v is now required", k)
+		}
+	}
+	for k := range o.ResponseHeaders {
+		if _, ok := n.ResponseHeaders[k]; !ok {
+			add(true, "Response header %#v was removed", k)
+		}
+	}
+
+	for _, d := range diffSchema("", o.InputParams, n.InputParams, true) {
+		add(d.breaking, "Path parameters: %s", d.message)
+	}
+	for _, d := range diffSchema("", o.QueryParams, n.QueryParams, true) {
+		add(d.breaking, "Query parameters: %s", d.message)
+	}
+	for _, d := range diffSchema("", o.RequestBody, n.RequestBody, true) {
+		add(d.breaking, "Request body: %s", d.message)
+	}
+	for _, d := range diffSchema("", o.ResponseBody, n.ResponseBody, false) {
+		add(d.breaking, "Response body: %s", d.message)
+	}
+
+	return changes
+}
+
+func (e Endpoint) requiresAuth() bool {
+	return Definition{Authenticated: e.Authenticated, Authentication: e.Authentication}.RequiresAuth()
+}
+
+type schemaChange struct {
+	breaking bool
+	message  string
+}
+
+// diffSchema compares two schemas. Input schemas, sent by callers, break when
+// they demand more. Output schemas, read by callers, break when they promise less.
+func diffSchema(path string, o, n *Schema, input bool) []schemaChange {
+	var changes []schemaChange
+
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, schemaChange{breaking, fmt.Sprintf(format, args...)})
+	}
+
+	name := path
+	if name == "" {
+		name = "body"
+	}
+
+	switch {
+	case o == nil && n == nil:
+		return nil
+	case o == nil:
+		add(input, "%s was added", name)
+		return changes
+	case n == nil:
+		add(!input, "%s was removed", name)
+		return changes
+	}
+
+	if o.Type != n.Type || o.Format != n.Format {
+		// Accepting a wider type as input, such as a number instead of an integer,
+		// is safe. Every other change of type is not.
+		widened := input && (o.Type == "integer" && n.Type == "number" ||
+			o.Type == n.Type && o.Format == "int32" && n.Format == "int64" ||
+			n.Type == "" && n.Format == "")
+		add(!widened, "%s type changed from %s to %s", name, o.typeName(), n.typeName())
+	}
+
+	if o.Nullable != n.Nullable {
+		if n.Nullable {
+			add(!input, "%s may now be null", name)
+		} else {
+			add(input, "%s may no longer be null", name)
+		}
+	}
+
+	props := map[string]bool{}
+	for k := range o.Properties {
+		props[k] = true
+	}
+	for k := range n.Properties {
+		props[k] = true
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		field := strings.TrimPrefix(path+"."+k, ".")
+		op, np := o.Properties[k], n.Properties[k]
+
+		switch {
+		case op == nil && input && n.requires(k):
+			add(true, "required field %#v was added", field)
+		case op == nil:
+			add(false, "field %#v was added", field)
+		case np == nil && input:
+			add(false, "field %#v was removed", field)
+		case np == nil:
+			add(true, "field %#v was removed", field)
+		default:
+			if input && !o.requires(k) && n.requires(k) {
+				add(true, "field %#v is now required", field)
+			}
+			if !input && o.requires(k) && !n.requires(k) {
+				add(true, "field %#v is no longer always present", field)
+			}
+			changes = append(changes, diffSchema(field, op, np, input)...)
+		}
+	}
+
+	if o.Items != nil || n.Items != nil {
+		changes = append(changes, diffSchema(name+"[]", o.Items, n.Items, input)...)
+	}
+	if o.AdditionalProperties != nil || n.AdditionalProperties != nil {
+		changes = append(changes, diffSchema(name+"{}", o.AdditionalProperties, n.AdditionalProperties, input)...)
+	}
+
+	return changes
+}
+
+func (s *Schema) typeName() string {
+	switch {
+	case s.Type == "":
+		return "any"
+	case s.Format != "":
+		return s.Type + "/" + s.Format
+	}
+	return s.Type
+}
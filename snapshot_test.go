@@ -0,0 +1,146 @@
+package truth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	user := func(alter func(e *Endpoint)) Snapshot {
+		e := Endpoint{
+			Method:           "GET",
+			Path:             "/users/{id}",
+			MIMETypeResponse: MIMETypeJSON,
+			ResponseBody: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"id": {Type: "integer"}, "name": {Type: "string"}},
+				Required:   []string{"id", "name"},
+			},
+		}
+		if alter != nil {
+			alter(&e)
+		}
+		return Snapshot{Endpoints: []Endpoint{e}}
+	}
+
+	tests := []struct {
+		name     string
+		after    Snapshot
+		changes  int
+		breaking int
+	}{
+		{name: "unchanged", after: user(nil)},
+		{name: "renamed route variable", after: user(func(e *Endpoint) { e.Path = "/users/{userID}" })},
+		{name: "removed endpoint", after: Snapshot{}, changes: 1, breaking: 1},
+		{name: "added endpoint", after: Snapshot{Endpoints: append(user(nil).Endpoints, Endpoint{Method: "POST", Path: "/users"})}, changes: 1},
+		{name: "authentication required", after: user(func(e *Endpoint) { e.Authenticated = true }), changes: 1, breaking: 1},
+		{name: "response field removed", after: user(func(e *Endpoint) {
+			e.ResponseBody = &Schema{Type: "object", Properties: map[string]*Schema{"id": {Type: "integer"}}, Required: []string{"id"}}
+		}), changes: 1, breaking: 1},
+		{name: "response field added", after: user(func(e *Endpoint) {
+			e.ResponseBody.Properties["email"] = &Schema{Type: "string"}
+		}), changes: 1},
+		{name: "request header required", after: user(func(e *Endpoint) {
+			e.RequestHeaders = map[string]string{"X-Tenant": ""}
+		}), changes: 1, breaking: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := DiffSnapshots(user(nil), test.after)
+			if len(changes) != test.changes {
+				t.Errorf("Expected %d changes but received %v", test.changes, changes)
+			}
+			if breaking := BreakingChanges(changes); len(breaking) != test.breaking {
+				t.Errorf("Expected %d breaking changes but received %v", test.breaking, breaking)
+			}
+		})
+	}
+}
+
+func TestMajorVersionChanged(t *testing.T) {
+	tests := []struct {
+		before, after string
+		expected      bool
+	}{
+		{before: "1.0.0", after: "1.2.0"},
+		{before: "v1.0", after: "1.3"},
+		{before: "1.9.0", after: "2.0.0", expected: true},
+		{before: "", after: "1", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.before+"->"+test.after, func(t *testing.T) {
+			if actual := MajorVersionChanged(Snapshot{APIVersion: test.before}, Snapshot{APIVersion: test.after}); actual != test.expected {
+				t.Errorf("Expected %t but received %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegisteredOnly(t *testing.T) {
+	baseline := Snapshot{Endpoints: []Endpoint{
+		{Method: "GET", Path: "/users/{id}"},
+		{Method: "DELETE", Path: "/users/{id}"},
+		{Method: "GET", Path: "/orders"},
+	}}
+	current := Snapshot{Endpoints: []Endpoint{{Method: "GET", Path: "/users/{userID}"}}}
+
+	changes := DiffSnapshots(registeredOnly(baseline, current), current)
+	if len(changes) != 0 {
+		t.Errorf("Expected endpoints which are not registered to be ignored but received %v", changes)
+	}
+}
+
+func TestCheckCompatibilityBaseline(t *testing.T) {
+	defer func(update bool) { updateFlag = update }(updateFlag)
+
+	Register(Definition{Method: "GET", Path: "/snapshot/users", MIMETypeResponse: MIMETypeJSON}, nil)
+	path := t.TempDir() + "/api.json"
+
+	updateFlag = true
+	CheckCompatibility(t, path, "1.0.0")
+	written, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Expected the baseline to be written with -truth.update but received %s", err)
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A change which is not breaking leaves the baseline alone without -truth.update.
+	updateFlag = false
+	Register(Definition{Method: "POST", Path: "/snapshot/users", MIMETypeResponse: MIMETypeJSON}, nil)
+	CheckCompatibility(t, path, "1.0.0")
+
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("Expected the baseline to be unchanged without -truth.update but received:\n%s", after)
+	}
+
+	// A filtered run does not write the baseline as endpoints may be missing.
+	if testsFiltered() {
+		return
+	}
+
+	updateFlag = true
+	CheckCompatibility(t, path, "1.0.0")
+	updated, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Endpoints) != len(written.Endpoints)+1 {
+		t.Errorf("Expected the baseline to be updated with -truth.update but it has %d endpoints", len(updated.Endpoints))
+	}
+}
+
+func TestLoadSnapshotMissing(t *testing.T) {
+	if _, err := LoadSnapshot(t.TempDir() + "/missing.json"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error but received %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package truth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The commands rendered to reproduce a request. See SetSnippets.
+const (
+	SnippetCurl   = "curl"
+	SnippetHTTPie = "httpie"
+)
+
+// snippet is a request ready to be rendered as a shell command.
+type snippet struct {
+	method string
+	url    string
+	header http.Header
+	body   []byte
+}
+
+var snippetsMu sync.Mutex
+
+// SetSnippets selects the commands, SnippetCurl and SnippetHTTPie, a failed
+// test case renders so the request it sent can be reproduced by hand. Without
+// any the request is not rendered. SetSnippets overrides the `-truth.snippets`
+// flag which renders curl by default.
+func SetSnippets(formats ...string) {
+	snippetsMu.Lock()
+	defer snippetsMu.Unlock()
+
+	if len(formats) == 0 {
+		formats = []string{"none"}
+	}
+	snippetsFlag = strings.Join(formats, ",")
+}
+
+// snippetFormats returns the commands selected by `-truth.snippets`.
+func snippetFormats() []string {
+	snippetsMu.Lock()
+	defer snippetsMu.Unlock()
+
+	if snippetsFlag == "" {
+		return []string{SnippetCurl}
+	}
+
+	var formats []string
+	for _, f := range strings.Split(snippetsFlag, ",") {
+		switch f = strings.ToLower(strings.TrimSpace(f)); f {
+		case SnippetCurl, SnippetHTTPie:
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// Curl renders the request as a curl command which can be pasted into a shell.
+// Requests without a host, such as those served in-process, are rendered with
+// http://localhost as their host.
+func Curl(req *http.Request) string {
+	return requestSnippet(req).curl()
+}
+
+// HTTPie renders the request as an HTTPie command which can be pasted into a
+// shell. See Curl.
+func HTTPie(req *http.Request) string {
+	return requestSnippet(req).httpie()
+}
+
+// Curl renders the request of the exchange as a curl command. Redacted values
+// are rendered as they were recorded.
+func (x Exchange) Curl() string {
+	return exchangeSnippet(x).curl()
+}
+
+// HTTPie renders the request of the exchange as an HTTPie command. See Curl.
+func (x Exchange) HTTPie() string {
+	return exchangeSnippet(x).httpie()
+}
+
+func requestSnippet(req *http.Request) snippet {
+	u := *req.URL
+	if u.Host == "" {
+		u.Scheme, u.Host = "http", "localhost"
+	}
+
+	// A body which cannot be read again is rendered without it.
+	body, _ := requestBody(req)
+
+	return snippet{method: req.Method, url: u.String(), header: req.Header, body: body}
+}
+
+func exchangeSnippet(x Exchange) snippet {
+	host := x.Host
+	if host == "" {
+		host = "http://localhost"
+	}
+	return snippet{method: x.Method, url: host + x.URL, header: x.RequestHeaders, body: x.RequestBody}
+}
+
+// reproduce renders the request made by the Client, which defaults to the
+// Client of the package, with the commands selected by `-truth.snippets` to be
+// appended to a failure message. Secrets are redacted as they are from recorded
+// exchanges, including the API keys registered with the Client, so credentials
+// do not leak into CI logs.
+func reproduce(req *http.Request, c *Client) string {
+	formats := snippetFormats()
+	if req == nil || len(formats) == 0 {
+		return ""
+	}
+
+	headers, fields := redactNames(c)
+	s := requestSnippet(req)
+	x := redact(Exchange{URL: s.url, RequestHeaders: s.header, RequestBody: s.body}, headers, fields)
+	s.url, s.header, s.body = x.URL, x.RequestHeaders, x.RequestBody
+
+	b := &strings.Builder{}
+	b.WriteString("\nReproduce with:")
+	for _, f := range formats {
+		switch f {
+		case SnippetCurl:
+			fmt.Fprintf(b, "\n%s", s.curl())
+		case SnippetHTTPie:
+			fmt.Fprintf(b, "\n%s", s.httpie())
+		}
+	}
+	return b.String()
+}
+
+// curl renders the request with one argument per line.
+func (s snippet) curl() string {
+	args := []string{"curl"}
+	switch {
+	case s.method == http.MethodHead:
+		args = append(args, "--head")
+	case s.method != http.MethodGet || len(s.body) > 0:
+		args = append(args, "-X "+s.method)
+	}
+	args = append(args, shellQuote(s.url))
+
+	for _, k := range sortedHeaderKeys(s.header) {
+		for _, v := range s.header[k] {
+			args = append(args, "-H "+shellQuote(k+": "+v))
+		}
+	}
+
+	if len(s.body) > 0 {
+		args = append(args, "--data-raw "+shellQuote(string(s.body)))
+	}
+
+	return strings.Join(args, " \\\n  ")
+}
+
+// httpie renders the request on a single line. The body is sent as it is with
+// --raw rather than built by HTTPie from fields.
+func (s snippet) httpie() string {
+	args := []string{"http"}
+	if len(s.body) > 0 {
+		args = append(args, "--raw", shellQuote(string(s.body)))
+	}
+	args = append(args, s.method, shellQuote(s.url))
+
+	for _, k := range sortedHeaderKeys(s.header) {
+		for _, v := range s.header[k] {
+			if v == "" {
+				// HTTPie sends a header without a value when it ends with `;`.
+				args = append(args, shellQuote(k+";"))
+				continue
+			}
+			args = append(args, shellQuote(k+":"+v))
+		}
+	}
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes the argument for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func sortedHeaderKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package truth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReproduceRedactsCredentials(t *testing.T) {
+	c := NewClient("http://localhost")
+	c.SetCredentials(AuthorizationCredentials, APIKey{Name: "key", Value: "secret", InQuery: true})
+	c.SetIdentity("service", APIKey{Name: "X-Service-Token", Value: "secret"})
+
+	tests := []struct {
+		name     string
+		url      string
+		header   string
+		expected string
+	}{
+		{name: "header", url: "http://localhost/users", header: "Authorization", expected: "Authorization: " + Redacted},
+		{name: "api key header", url: "http://localhost/users", header: "X-API-Key", expected: "X-Api-Key: " + Redacted},
+		{name: "registered api key header", url: "http://localhost/users", header: "X-Service-Token", expected: "X-Service-Token: " + Redacted},
+		{name: "api key in query", url: "http://localhost/users?key=secret&page=2", expected: "key=" + Redacted + "&page=2"},
+		{name: "redacted field in query", url: "http://localhost/users?Access_Token=secret", expected: "Access_Token=" + Redacted},
+		{name: "other query", url: "http://localhost/users?page=2&secretive=1", expected: "?page=2&secretive=1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", test.url, nil)
+			if test.header != "" {
+				req.Header.Set(test.header, "secret")
+			}
+
+			actual := reproduce(req, c)
+			if !strings.Contains(actual, test.expected) {
+				t.Errorf("Expected %s within:\n%s", test.expected, actual)
+			}
+			if strings.Contains(strings.ToLower(actual), "=secret") || strings.Contains(actual, ": secret") {
+				t.Errorf("Expected the credentials to be redacted but received:\n%s", actual)
+			}
+		})
+	}
+}
+
+// TestReproduceInProcess checks the credentials of the Client of the package
+// are redacted from requests made in-process, without a Client.
+func TestReproduceInProcess(t *testing.T) {
+	withClient(t, NewClient(""))
+	SetCredentials(AuthorizationCredentials, APIKey{Name: "X-Service-Token", Value: "secret"})
+	SetIdentity("partner", APIKey{Name: "sig", Value: "secret", InQuery: true})
+
+	tests := []struct {
+		name     string
+		tc       TestCase
+		expected string
+	}{
+		{name: "api key header", tc: TestCase{Path: "/users"}, expected: "X-Service-Token: " + Redacted},
+		{name: "api key in query", tc: TestCase{Path: "/users?page=2", Identity: Identity("partner")}, expected: "page=2&sig=" + Redacted},
+	}
+
+	def := Definition{Method: "GET", Path: "/users", Authenticated: true, MIMETypeRequest: MIMETypeJSON, MIMETypeResponse: MIMETypeJSON}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := integrationClient.BuildRequest(def, test.tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := reproduce(req, nil)
+			if !strings.Contains(actual, test.expected) {
+				t.Errorf("Expected %s within:\n%s", test.expected, actual)
+			}
+			if strings.Contains(actual, "secret") {
+				t.Errorf("Expected the credentials to be redacted but received:\n%s", actual)
+			}
+		})
+	}
+}
+
+func TestEndpointSnippetCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		cred     Credentials
+		auth     string
+		url      string
+		header   string
+		expected string
+	}{
+		{name: "bearer", cred: Bearer("secret"), url: "http://localhost/users", header: "Authorization", expected: "<credentials>"},
+		{name: "unregistered", url: "http://localhost/users", header: "Authorization", expected: "<credentials>"},
+		{name: "api key header", cred: APIKey{Name: "X-API-Key", Value: "secret"}, url: "http://localhost/users", header: "X-API-Key", expected: "<credentials>"},
+		{name: "api key in query", cred: APIKey{Name: "key", Value: "secret", InQuery: true}, url: "http://localhost/users?key=<credentials>"},
+		{name: "session cookie", cred: SessionCookie{Name: "session", Value: "secret"}, url: "http://localhost/users", header: "Cookie", expected: "session=<credentials>"},
+		{name: "checksum", auth: AuthenticationChecksum, url: "http://localhost/users", header: SignatureHeader, expected: "<signature>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auth := test.auth
+			if auth == "" {
+				auth = AuthorizationCredentials
+			}
+			creds := map[string]Credentials{}
+			if test.cred != nil {
+				creds[auth] = test.cred
+			}
+
+			s := endpointSnippet(Endpoint{Method: "GET", Path: "/users", Authenticated: true, Authentication: test.auth}, creds)
+			if s.url != test.url {
+				t.Errorf("Expected %s but received %s", test.url, s.url)
+			}
+			if test.header != "" && s.header.Get(test.header) != test.expected {
+				t.Errorf("Expected %s %q but received %q", test.header, test.expected, s.header.Get(test.header))
+			}
+			if test.header != "Authorization" && s.header.Get("Authorization") != "" {
+				t.Errorf("Expected no Authorization header but received %q", s.header.Get("Authorization"))
+			}
+		})
+	}
+}
@@ -22,11 +22,19 @@ type (
 
 		Result interface{}
 
+		// Identity overrides the credentials the Client selects for the Definition.
+		// Use an Identity to choose credentials registered by name.
+		Identity Credentials
+
+		// Tags select the test case with the `-truth.tags` flag such as "smoke".
+		Tags []string
+
 		Verbose     bool
 		Integration func(Integration)
 		Unit        func(Unit)
 
 		alias string // Used for test failure messages
+		label string // Name without the caller, stable across edits
 	}
 
 	Integration struct {
@@ -52,7 +60,8 @@ func (tc *TestCase) init(def Definition, n, count int, caller string) {
 	}
 
 	if tc.Name == "" {
-		tc.Name = fmt.Sprintf("'%s:%s' (%d of %d) called from %s", def.Method, def.Path, n+1, count+1, caller)
+		tc.label = fmt.Sprintf("'%s:%s' (%d of %d)", def.Method, def.Path, n+1, count+1)
+		tc.Name = tc.label + " called from " + caller
 	}
 
 	tc.alias = "Testcase: " + tc.Name